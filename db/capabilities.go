@@ -0,0 +1,102 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Capabilities tells which of the features used by the screens are
+// available on a server, see GetServerInfo for how they are probed.
+type Capabilities struct {
+	Major   int
+	Minor   int
+	Patch   int
+	MariaDB bool
+
+	PerformanceSchema bool
+	SysSchema         bool
+	// ErrorLog is the performance_schema.error_log table
+	ErrorLog bool
+}
+
+// NewCapabilities reads the capabilities from a row of GetServerInfo
+func NewCapabilities(cols []string, row []string) Capabilities {
+	var caps Capabilities
+	values := make(map[string]string)
+	for i, col := range cols {
+		if i < len(row) {
+			values[col] = row[i]
+		}
+	}
+
+	version := values["version"]
+	caps.MariaDB = strings.Contains(strings.ToLower(version+" "+values["version_comment"]), "mariadb")
+	// 8.0.22-log, 5.7.33-36, 10.5.8-MariaDB-1:10.5.8+maria~focal
+	if i := strings.IndexAny(version, "-+~ "); i >= 0 {
+		version = version[:i]
+	}
+	numbers := strings.SplitN(version, ".", 3)
+	for i, number := range numbers {
+		n, _ := strconv.Atoi(number)
+		switch i {
+		case 0:
+			caps.Major = n
+		case 1:
+			caps.Minor = n
+		case 2:
+			caps.Patch = n
+		}
+	}
+
+	caps.PerformanceSchema = values["performance_schema"] == "1" || strings.EqualFold(values["performance_schema"], "ON")
+	caps.SysSchema = values["sys_schema"] != "" && values["sys_schema"] != "0"
+	caps.ErrorLog = values["error_log"] != "" && values["error_log"] != "0"
+	return caps
+}
+
+// AtLeast tells if the version of the server is major.minor.patch or later
+func (caps Capabilities) AtLeast(major, minor, patch int) bool {
+	if caps.Major != major {
+		return caps.Major > major
+	}
+	if caps.Minor != minor {
+		return caps.Minor > minor
+	}
+	return caps.Patch >= patch
+}
+
+// MySQL8 is true for MySQL 8.0 and later, MariaDB took another path
+func (caps Capabilities) MySQL8() bool {
+	return !caps.MariaDB && caps.AtLeast(8, 0, 0)
+}
+
+// Supported is true for MySQL 5.7 and later, and MariaDB 10 and later
+func (caps Capabilities) Supported() bool {
+	if caps.MariaDB {
+		return caps.Major >= 10
+	}
+	return caps.AtLeast(5, 7, 0)
+}
+
+// FormatPicoTime is true when format_pico_time() and format_bytes() exist
+// (MySQL 8.0.16)
+func (caps Capabilities) FormatPicoTime() bool {
+	return caps.MySQL8() && caps.AtLeast(8, 0, 16)
+}
+
+// QuerySampleText is true when the statement digests keep a sample query
+// (MySQL 8.0.3)
+func (caps Capabilities) QuerySampleText() bool {
+	return caps.MySQL8() && caps.AtLeast(8, 0, 3)
+}
+
+// LogStatus is true when performance_schema.log_status exists (MySQL 8.0.17)
+func (caps Capabilities) LogStatus() bool {
+	return caps.MySQL8() && caps.AtLeast(8, 0, 17)
+}
+
+// ReplicaStatements is true when SHOW REPLICA STATUS and SHOW REPLICAS
+// exist (MySQL 8.0.22)
+func (caps Capabilities) ReplicaStatements() bool {
+	return caps.MySQL8() && caps.AtLeast(8, 0, 22)
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Fixture is the canned answer of a Fake to a statement. Without Args it
+// answers the statement whatever its arguments.
+type Fixture struct {
+	Statement string          `json:"statement"`
+	Args      []interface{}   `json:"args,omitempty"`
+	Columns   []string        `json:"columns,omitempty"`
+	Rows      [][]interface{} `json:"rows,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Fake is a Querier answering canned results keyed by statement, so that
+// the screens can run without a server. The statements are compared with
+// their whitespace collapsed.
+type Fake struct {
+	mutex    sync.Mutex
+	fixtures map[string]Fixture
+	executed []string
+}
+
+func NewFake(fixtures ...Fixture) *Fake {
+	fake := &Fake{fixtures: map[string]Fixture{}}
+	for _, fixture := range fixtures {
+		fake.Add(fixture)
+	}
+	return fake
+}
+
+// LoadFake reads the fixtures from a JSON array, like:
+//
+//	[{"statement": "select @@version", "columns": ["@@version"], "rows": [["8.0.36"]]}]
+func LoadFake(file string) (*Fake, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	decoder := json.NewDecoder(in)
+	// keep the numbers as written
+	decoder.UseNumber()
+	var fixtures []Fixture
+	if err := decoder.Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return NewFake(fixtures...), nil
+}
+
+func (fake *Fake) Add(fixture Fixture) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.fixtures[fixtureKey(fixture.Statement, fixture.Args)] = fixture
+}
+
+// Set answers stmt with the rows, whatever its arguments
+func (fake *Fake) Set(stmt string, cols []string, rows ...[]interface{}) {
+	fake.Add(Fixture{Statement: stmt, Columns: cols, Rows: rows})
+}
+
+// Executed returns the statements run with ExecContext
+func (fake *Fake) Executed() []string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return append([]string{}, fake.executed...)
+}
+
+func (fake *Fake) fixture(stmt string, args []interface{}) (Fixture, bool) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fixture, ok := fake.fixtures[fixtureKey(stmt, args)]
+	if !ok {
+		fixture, ok = fake.fixtures[fixtureKey(stmt, nil)]
+	}
+	return fixture, ok
+}
+
+func (fake *Fake) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	fixture, ok := fake.fixture(stmt, args)
+	if !ok {
+		return nil, fmt.Errorf("no fixture for statement: %s", normalizeStatement(stmt))
+	}
+	if len(fixture.Error) > 0 {
+		return nil, errors.New(fixture.Error)
+	}
+	return NewResult(fixture.Columns, fixture.Rows), nil
+}
+
+// ExecContext records stmt, it fails only when a fixture gives an error
+func (fake *Fake) ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	fake.mutex.Lock()
+	fake.executed = append(fake.executed, normalizeStatement(stmt))
+	fake.mutex.Unlock()
+	if fixture, ok := fake.fixture(stmt, args); ok && len(fixture.Error) > 0 {
+		return -1, errors.New(fixture.Error)
+	}
+	return 0, nil
+}
+
+func (fake *Fake) Session(ctx context.Context) (*Session, error) {
+	return &Session{Querier: fake, close: func() {}}, nil
+}
+
+func (fake *Fake) PingContext(ctx context.Context) error {
+	return nil
+}
+
+func (fake *Fake) Close() error {
+	return nil
+}
+
+func fixtureKey(stmt string, args []interface{}) string {
+	key := normalizeStatement(stmt)
+	for _, arg := range args {
+		key = key + "\x00" + fmt.Sprint(arg)
+	}
+	return key
+}
+
+// normalizeStatement returns the statement on a single line
+func normalizeStatement(stmt string) string {
+	return strings.Join(strings.Fields(stmt), " ")
+}
@@ -3,106 +3,109 @@ package db
 import (
 	"context"
 	"database/sql"
-	"fmt"
-	"strings"
+	"net"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lefred/innotopgo/parse"
 )
 
-func Connect(mysql_uri string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", mysql_uri)
+// Connect opens the pool of connections to the server called name, the
+// name keeps apart the TLS and SSH settings of servers sharing an address
+func Connect(name string, cfg parse.Config) (Querier, error) {
+	mysql_cfg := mysqlConfig(cfg)
+	var err error
+	mysql_cfg.TLSConfig, err = registerTLS(name, cfg)
 	if err != nil {
 		return nil, err
 	}
-	return db, nil
+	if len(cfg.SSH) > 0 {
+		mysql_cfg.Net, err = registerSSH(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the connector avoids formatting the DSN again, the name of the ssh
+	// network does not fit in it
+	connector, err := mysql.NewConnector(mysql_cfg)
+	if err != nil {
+		return nil, err
+	}
+	return poolQuerier{db: sql.OpenDB(connector)}, nil
 }
 
-func QueryTimeout(ctx context.Context, db *sql.DB, stmt string) (*sql.Rows, error) {
+// mysqlConfig returns the driver settings of cfg, the user and the password
+// are not formatted in a DSN where '@', ':' or '/' would end them
+func mysqlConfig(cfg parse.Config) *mysql.Config {
+	mysql_cfg := mysql.NewConfig()
+	mysql_cfg.User = cfg.User
+	mysql_cfg.Passwd = cfg.Password
+	if len(cfg.Socket) > 0 {
+		mysql_cfg.Net = "unix"
+		mysql_cfg.Addr = cfg.Socket
+	} else {
+		mysql_cfg.Net = "tcp"
+		mysql_cfg.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	}
+	// do not wait for the system timeout when the server is unreachable
+	mysql_cfg.Timeout = 10 * time.Second
+	return mysql_cfg
+}
+
+func QueryTimeout(ctx context.Context, mydb Querier, stmt string, args ...interface{}) (*Result, error) {
 	queryctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(queryctx, stmt)
+	return QueryContext(queryctx, mydb, stmt, args...)
+}
 
-	//rows, err := db.Query(stmt)
-	if err != nil {
-		return nil, err
-	}
-	return rows, nil
+// Query runs stmt, the values coming from outside the statement must be
+// passed as args and used through ? placeholders
+func Query(mydb Querier, stmt string, args ...interface{}) (*Result, error) {
+	return QueryContext(context.Background(), mydb, stmt, args...)
 }
 
-func Query(db *sql.DB, stmt string) (*sql.Rows, error) {
-	rows, err := db.Query(stmt)
-	if err != nil {
-		return nil, err
+func QueryContext(ctx context.Context, mydb Querier, stmt string, args ...interface{}) (*Result, error) {
+	trace := startTrace(stmt)
+	result, err := mydb.QueryContext(ctx, stmt, args...)
+	count := int64(-1)
+	if err == nil {
+		count = int64(len(result.Rows))
 	}
-	return rows, nil
+	endTrace(trace, count, err)
+	return result, err
 }
 
-func RunQuery(db *sql.DB, stmt string) error {
-	_, err := db.Exec(stmt)
-	if err != nil {
-		return err
-	}
-	return nil
+func RunQuery(mydb Querier, stmt string, args ...interface{}) error {
+	return RunQueryContext(context.Background(), mydb, stmt, args...)
 }
 
-func GetData(rows *sql.Rows) ([]string, [][]string, error) {
-	var result [][]string
-	defer rows.Close()
+func RunQueryContext(ctx context.Context, mydb Querier, stmt string, args ...interface{}) error {
+	trace := startTrace(stmt)
+	count, err := mydb.ExecContext(ctx, stmt, args...)
+	endTrace(trace, count, err)
+	return err
+}
 
-	colTypes, err := rows.ColumnTypes()
+// GetData runs stmt and returns its rows as strings, see Query for the
+// typed values
+func GetData(mydb Querier, stmt string, args ...interface{}) ([]string, [][]string, error) {
+	result, err := Query(mydb, stmt, args...)
 	if err != nil {
 		return nil, nil, err
 	}
-	cols, err := rows.Columns()
-	if err != nil {
-		return nil, nil, err
-	}
-	vals := make([]interface{}, len(cols))
-	for i, _ := range cols {
-		vals[i] = new(sql.RawBytes)
-	}
-	for rows.Next() {
-		err = rows.Scan(vals...)
-		if err != nil {
-			return nil, nil, err
-		}
-		var resultRow []string
-		for i, col := range vals {
-			var value string
-			if col == nil {
-				value = "NULL"
-			} else {
-				switch colTypes[i].DatabaseTypeName() {
-				case "VARCHAR", "CHAR", "TEXT":
-					value = fmt.Sprintf("%s", col)
-				case "BIGINT":
-					value = fmt.Sprintf("%s", col)
-				case "INT":
-					//value = fmt.Sprintf("%d", col)
-					value = fmt.Sprintf("%s", col)
-				case "DECIMAL":
-					value = fmt.Sprintf("%s", col)
-				default:
-					value = fmt.Sprintf("%s", col)
-				}
-			}
-			value = strings.Replace(value, "&", "", 1)
-			resultRow = append(resultRow, value)
-		}
-		result = append(result, resultRow)
-	}
-	return cols, result, nil
+	return result.Columns, result.Strings(), nil
 }
 
-func GetServerInfo(mydb *sql.DB) ([]string, [][]string, error) {
-	stmt := `select @@version_comment, @@version, @@hostname, @@port`
-	rows, err := Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := GetData(rows)
+func GetServerInfo(mydb Querier) ([]string, [][]string, error) {
+	stmt := `select @@version_comment as version_comment, @@version as version,
+	                @@hostname as hostname, @@port as port,
+	                @@performance_schema as performance_schema,
+	                (select count(*) from information_schema.schemata
+	                  where schema_name = 'sys') as sys_schema,
+	                (select count(*) from information_schema.tables
+	                  where table_schema = 'performance_schema' and table_name = 'error_log') as error_log`
+	cols, data, err := GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
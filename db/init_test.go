@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lefred/innotopgo/parse"
+)
+
+func TestMySQLConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  parse.Config
+		net  string
+		addr string
+	}{
+		{name: "tcp", cfg: parse.Config{User: "app", Password: "p@ss:w/rd", Host: "db1", Port: "3307"}, net: "tcp", addr: "db1:3307"},
+		{name: "ipv6", cfg: parse.Config{User: "app", Password: "a/b@c", Host: "::1", Port: "3306"}, net: "tcp", addr: "[::1]:3306"},
+		{name: "socket", cfg: parse.Config{User: "root@x", Password: "a:b@c/d", Socket: "/tmp/mysql.sock", Host: "localhost", Port: "3306"}, net: "unix", addr: "/tmp/mysql.sock"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mysql_cfg := mysqlConfig(test.cfg)
+			if mysql_cfg.User != test.cfg.User || mysql_cfg.Passwd != test.cfg.Password {
+				t.Errorf("credentials are %s/%s, want %s/%s", mysql_cfg.User, mysql_cfg.Passwd, test.cfg.User, test.cfg.Password)
+			}
+			if mysql_cfg.Net != test.net || mysql_cfg.Addr != test.addr {
+				t.Errorf("address is %s(%s), want %s(%s)", mysql_cfg.Net, mysql_cfg.Addr, test.net, test.addr)
+			}
+			// the driver formats and parses the DSN of the connector again
+			parsed, err := mysql.ParseDSN(mysql_cfg.FormatDSN())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Passwd != test.cfg.Password || parsed.Addr != test.addr {
+				t.Errorf("formatted DSN gives %s/%s, want %s/%s", parsed.Passwd, parsed.Addr, test.cfg.Password, test.addr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// Querier runs the statements of the screens. It is the connection pool of
+// a server returned by Connect, a Session or a Fake.
+type Querier interface {
+	// QueryContext runs stmt and reads all its rows
+	QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error)
+	// ExecContext runs stmt and returns the number of affected rows
+	ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error)
+	// Session returns a dedicated connection for the statements changing
+	// the session state
+	Session(ctx context.Context) (*Session, error)
+	PingContext(ctx context.Context) error
+	Close() error
+}
+
+// poolQuerier runs the statements on any connection of the pool
+type poolQuerier struct {
+	db *sql.DB
+}
+
+func (pool poolQuerier) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	rows, err := pool.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return GetRows(rows)
+}
+
+func (pool poolQuerier) ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	result, err := pool.db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return -1, err
+	}
+	return result.RowsAffected()
+}
+
+func (pool poolQuerier) Session(ctx context.Context) (*Session, error) {
+	conn, err := pool.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		Querier: connQuerier{conn: conn},
+		close: func() {
+			// a connection reported as bad is discarded by database/sql
+			// instead of going back to the pool with its session state
+			conn.Raw(func(interface{}) error {
+				return driver.ErrBadConn
+			})
+			conn.Close()
+		},
+	}, nil
+}
+
+func (pool poolQuerier) PingContext(ctx context.Context) error {
+	return pool.db.PingContext(ctx)
+}
+
+func (pool poolQuerier) Close() error {
+	return pool.db.Close()
+}
+
+// connQuerier runs the statements on a single connection
+type connQuerier struct {
+	conn *sql.Conn
+}
+
+func (c connQuerier) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	rows, err := c.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return GetRows(rows)
+}
+
+func (c connQuerier) ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	result, err := c.conn.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return -1, err
+	}
+	return result.RowsAffected()
+}
+
+// Session returns a session on the same connection, closing it does nothing
+func (c connQuerier) Session(ctx context.Context) (*Session, error) {
+	return &Session{Querier: c, close: func() {}}, nil
+}
+
+func (c connQuerier) PingContext(ctx context.Context) error {
+	return c.conn.PingContext(ctx)
+}
+
+func (c connQuerier) Close() error {
+	return c.conn.Close()
+}
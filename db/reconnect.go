@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	reconnect_min_delay = 1 * time.Second
+	reconnect_max_delay = 30 * time.Second
+	ping_timeout        = 5 * time.Second
+)
+
+// MySQL errors sent when the server goes away
+const (
+	er_server_shutdown            = 1053
+	er_client_interaction_timeout = 4031
+)
+
+// Reconnector tracks the state of the connection to a server. Once the
+// connection is lost, the server is checked again after a delay doubling at
+// each failed attempt. database/sql opens new connections by itself, the
+// delay only avoids hammering a server that is restarting.
+type Reconnector struct {
+	mutex    sync.Mutex
+	lost     bool
+	err      error
+	attempts int
+	retry_at time.Time
+}
+
+// IsConnectionLost tells if err means the server cannot be reached anymore
+// rather than a failed query. A query cancelled or running past its deadline
+// is a slow query, only a timeout while dialing means the server is gone.
+func IsConnectionLost(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var op_err *net.OpError
+	if errors.As(err, &op_err) && op_err.Op == "dial" {
+		return true
+	}
+	var net_err net.Error
+	if errors.As(err, &net_err) {
+		return !net_err.Timeout()
+	}
+	var channel_err *ssh.OpenChannelError
+	if errors.As(err, &channel_err) {
+		// the bastion cannot reach the server
+		return true
+	}
+	var mysql_err *mysql.MySQLError
+	if errors.As(err, &mysql_err) {
+		return mysql_err.Number == er_server_shutdown || mysql_err.Number == er_client_interaction_timeout
+	}
+	return false
+}
+
+// Lost records err and returns true when it means the connection was lost
+func (r *Reconnector) Lost(err error) bool {
+	if !IsConnectionLost(err) {
+		return false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fail(err)
+	return true
+}
+
+func (r *Reconnector) fail(err error) {
+	delay := reconnect_min_delay << uint(r.attempts)
+	if delay > reconnect_max_delay || delay <= 0 {
+		delay = reconnect_max_delay
+	} else {
+		r.attempts++
+	}
+	r.lost = true
+	r.err = err
+	r.retry_at = time.Now().Add(delay)
+}
+
+// Check returns true when queries can be sent to the server. When the
+// connection was lost and the retry delay expired, the server is pinged.
+func (r *Reconnector) Check(mydb Querier) bool {
+	r.mutex.Lock()
+	if !r.lost {
+		r.mutex.Unlock()
+		return true
+	}
+	if time.Now().Before(r.retry_at) {
+		r.mutex.Unlock()
+		return false
+	}
+	r.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ping_timeout)
+	defer cancel()
+	err := mydb.PingContext(ctx)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil {
+		r.fail(err)
+		return false
+	}
+	r.lost = false
+	r.err = nil
+	r.attempts = 0
+	return true
+}
+
+// Status returns whether the connection is lost, the time left before the
+// next attempt and the last error.
+func (r *Reconnector) Status() (bool, time.Duration, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.lost {
+		return false, 0, nil
+	}
+	retry_in := time.Until(r.retry_at)
+	if retry_in < 0 {
+		retry_in = 0
+	}
+	return true, retry_in, r.err
+}
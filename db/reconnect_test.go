@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/ssh"
+)
+
+// timeoutError is a net.Error like the ones of a read past its deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsConnectionLost(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		lost bool
+	}{
+		{name: "bad connection", err: driver.ErrBadConn, lost: true},
+		{name: "invalid connection", err: mysql.ErrInvalidConn, lost: true},
+		{name: "eof", err: fmt.Errorf("read: %w", io.EOF), lost: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errors.New("connection refused"))}, lost: true},
+		{name: "dial timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, lost: true},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", errors.New("connection reset by peer"))}, lost: true},
+		{name: "read timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}},
+		{name: "deadline exceeded", err: context.DeadlineExceeded},
+		{name: "wrapped deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded)},
+		{name: "canceled", err: context.Canceled},
+		{name: "bastion cannot reach the server", err: &ssh.OpenChannelError{Reason: ssh.ConnectionFailed}, lost: true},
+		{name: "server shutdown", err: &mysql.MySQLError{Number: er_server_shutdown}, lost: true},
+		{name: "syntax error", err: &mysql.MySQLError{Number: 1064}},
+		{name: "other error", err: errors.New("table does not exist")},
+	}
+	for _, test := range tests {
+		if lost := IsConnectionLost(test.err); lost != test.lost {
+			t.Errorf("%s: IsConnectionLost = %v, want %v", test.name, lost, test.lost)
+		}
+	}
+}
+
+func TestReconnectorIgnoresTimeouts(t *testing.T) {
+	var r Reconnector
+	if r.Lost(fmt.Errorf("query: %w", context.DeadlineExceeded)) {
+		t.Errorf("a query past its deadline was taken for a lost connection")
+	}
+	if lost, _, _ := r.Status(); lost {
+		t.Errorf("the connection is marked lost after a timeout")
+	}
+	if !r.Lost(driver.ErrBadConn) {
+		t.Errorf("a bad connection was not taken for a lost connection")
+	}
+	if lost, retry_in, err := r.Status(); !lost || retry_in <= 0 || err != driver.ErrBadConn {
+		t.Errorf("Status = %v, %v, %v after a lost connection", lost, retry_in, err)
+	}
+}
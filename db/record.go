@@ -0,0 +1,138 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// record_version is increased when the format of the recordings changes
+const record_version = 1
+
+// A recording is a gzip compressed gob stream: a recordHeader followed by a
+// recordEntry for each result read from the servers.
+type recordHeader struct {
+	Version  int
+	Start    time.Time
+	Interval time.Duration
+	Servers  []string
+}
+
+type recordEntry struct {
+	Time    time.Time
+	Server  string
+	Screen  string
+	Stmt    string
+	Args    []string
+	Columns []string
+	Types   []string
+	Rows    [][]recordValue
+}
+
+type recordValue struct {
+	Raw  []byte
+	Null bool
+}
+
+var recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	gz      *gzip.Writer
+	encoder *gob.Encoder
+}
+
+// OpenRecord starts recording in file the results of the statements run
+// through the queriers returned by Record
+func OpenRecord(file string, interval time.Duration, servers []string) error {
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	encoder := gob.NewEncoder(gz)
+	header := recordHeader{Version: record_version, Start: time.Now(), Interval: interval, Servers: servers}
+	if err := encoder.Encode(header); err != nil {
+		out.Close()
+		return err
+	}
+	recorder.mutex.Lock()
+	recorder.file, recorder.gz, recorder.encoder = out, gz, encoder
+	recorder.mutex.Unlock()
+	return nil
+}
+
+func CloseRecord() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.file != nil {
+		recorder.gz.Close()
+		recorder.file.Close()
+		recorder.file = nil
+	}
+}
+
+// Record returns a Querier saving the results of mydb in the recording
+// under the name of the server
+func Record(mydb Querier, server string) Querier {
+	return recordQuerier{Querier: mydb, server: server}
+}
+
+type recordQuerier struct {
+	Querier
+	server string
+}
+
+// QueryContext records the results, the failed statements are not recorded
+func (r recordQuerier) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	result, err := r.Querier.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	entry := recordEntry{
+		Time:    time.Now(),
+		Server:  r.server,
+		Screen:  currentScreen(),
+		Stmt:    normalizeStatement(stmt),
+		Columns: result.Columns,
+	}
+	for _, arg := range args {
+		entry.Args = append(entry.Args, fmt.Sprint(arg))
+	}
+	for _, row := range result.Rows {
+		values := make([]recordValue, len(row.Values))
+		for i, value := range row.Values {
+			values[i] = recordValue{Raw: value.raw, Null: value.null}
+			if len(entry.Types) < len(row.Values) {
+				entry.Types = append(entry.Types, value.kind)
+			}
+		}
+		entry.Rows = append(entry.Rows, values)
+	}
+	writeRecord(entry)
+	return result, nil
+}
+
+// Session keeps recording the statements of the session
+func (r recordQuerier) Session(ctx context.Context) (*Session, error) {
+	session, err := r.Querier.Session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Querier: recordQuerier{Querier: session.Querier, server: r.server}, close: session.close}, nil
+}
+
+func writeRecord(entry recordEntry) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.file == nil {
+		return
+	}
+	if err := recorder.encoder.Encode(entry); err == nil {
+		// keep the recording readable if innotopgo is killed
+		recorder.gz.Flush()
+	}
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replay_speeds are the playback speeds, 1 being real time
+var replay_speeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32}
+
+// Replay plays back a recording: the Querier of each recorded server answers
+// a statement with its result recorded at the time of the playback clock.
+type Replay struct {
+	Interval time.Duration
+	Servers  []string
+	start    time.Time
+	end      time.Time
+	// results of each server by statement, in the order of their time
+	results map[string]map[string][]recordEntry
+	// arguments of each server by statement, a statement run with other
+	// arguments is answered with its results when it was recorded with
+	// only one set of them
+	arguments map[string]map[string][]string
+	screens   map[string]bool
+
+	mutex sync.Mutex
+	// the clock was at position at the time anchor
+	position time.Time
+	anchor   time.Time
+	speed    int
+	paused   bool
+}
+
+// OpenReplay reads a recording made with OpenRecord
+func OpenReplay(file string) (*Replay, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a recording: %v", file, err)
+	}
+	decoder := gob.NewDecoder(gz)
+	var header recordHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("%s is not a recording: %v", file, err)
+	}
+	if header.Version != record_version {
+		return nil, fmt.Errorf("%s: unsupported recording version %d", file, header.Version)
+	}
+
+	replay := &Replay{
+		Interval:  header.Interval,
+		Servers:   header.Servers,
+		start:     header.Start,
+		end:       header.Start,
+		results:   map[string]map[string][]recordEntry{},
+		arguments: map[string]map[string][]string{},
+		screens:   map[string]bool{},
+		speed:     2,
+	}
+	for {
+		var entry recordEntry
+		err := decoder.Decode(&entry)
+		// the end of a recording interrupted by a crash is lost
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if replay.results[entry.Server] == nil {
+			replay.results[entry.Server] = map[string][]recordEntry{}
+			replay.arguments[entry.Server] = map[string][]string{}
+		}
+		key := recordKey(entry.Stmt, entry.Args)
+		if _, ok := replay.results[entry.Server][key]; !ok {
+			replay.arguments[entry.Server][entry.Stmt] = append(replay.arguments[entry.Server][entry.Stmt], key)
+		}
+		replay.results[entry.Server][key] = append(replay.results[entry.Server][key], entry)
+		replay.screens[entry.Screen] = true
+		if entry.Time.After(replay.end) {
+			replay.end = entry.Time
+		}
+	}
+	replay.position = replay.start
+	replay.anchor = time.Now()
+	return replay, nil
+}
+
+// Querier returns the Querier playing back the results of server
+func (replay *Replay) Querier(server string) Querier {
+	return replayQuerier{replay: replay, server: server}
+}
+
+// Recorded tells if a screen was displayed during the recording
+func (replay *Replay) Recorded(screen string) bool {
+	return replay.screens[screen]
+}
+
+// Now returns the time of the playback clock, it stops at the end of the
+// recording
+func (replay *Replay) Now() time.Time {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	return replay.now()
+}
+
+func (replay *Replay) now() time.Time {
+	now := replay.position
+	if !replay.paused {
+		elapsed := float64(time.Since(replay.anchor)) * replay_speeds[replay.speed]
+		now = now.Add(time.Duration(elapsed))
+	}
+	if now.After(replay.end) {
+		now = replay.end
+	}
+	return now
+}
+
+// Status returns the time of the playback clock, its speed and whether it
+// is paused
+func (replay *Replay) Status() (time.Time, float64, bool) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	now := replay.now()
+	return now, replay_speeds[replay.speed], replay.paused || !now.Before(replay.end)
+}
+
+// anchorNow restarts the clock from its current time
+func (replay *Replay) anchorNow() {
+	replay.position = replay.now()
+	replay.anchor = time.Now()
+}
+
+func (replay *Replay) TogglePause() {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.anchorNow()
+	replay.paused = !replay.paused
+}
+
+// Seek moves the clock by d, backward when d is negative
+func (replay *Replay) Seek(d time.Duration) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.anchorNow()
+	replay.position = replay.position.Add(d)
+	if replay.position.Before(replay.start) {
+		replay.position = replay.start
+	}
+	if replay.position.After(replay.end) {
+		replay.position = replay.end
+	}
+}
+
+// Step pauses the playback and moves the clock by one refresh interval
+func (replay *Replay) Step(forward bool) {
+	replay.mutex.Lock()
+	replay.anchorNow()
+	replay.paused = true
+	replay.mutex.Unlock()
+	if forward {
+		replay.Seek(replay.Interval)
+	} else {
+		replay.Seek(-replay.Interval)
+	}
+}
+
+// Faster speeds the playback up, or slows it down when faster is false
+func (replay *Replay) Faster(faster bool) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.anchorNow()
+	if faster && replay.speed < len(replay_speeds)-1 {
+		replay.speed++
+	} else if !faster && replay.speed > 0 {
+		replay.speed--
+	}
+}
+
+type replayQuerier struct {
+	replay *Replay
+	server string
+}
+
+// QueryContext returns the last result recorded before the playback clock,
+// or the first one when the statement was not run yet. The arguments
+// depending on the refresh interval, like the window of Top Queries, differ
+// when the interval of the playback is not the one of the recording: a
+// statement always recorded with the same arguments is answered whatever
+// its arguments.
+func (r replayQuerier) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	var str_args []string
+	for _, arg := range args {
+		str_args = append(str_args, fmt.Sprint(arg))
+	}
+	stmt = normalizeStatement(stmt)
+	entries := r.replay.results[r.server][recordKey(stmt, str_args)]
+	if keys := r.replay.arguments[r.server][stmt]; len(entries) == 0 && len(keys) == 1 {
+		entries = r.replay.results[r.server][keys[0]]
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("this statement was not recorded: %s", stmt)
+	}
+	now := r.replay.Now()
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Time.After(now)
+	})
+	if i > 0 {
+		i--
+	}
+	entry := entries[i]
+	result := &Result{Columns: entry.Columns}
+	columns := columnIndex(entry.Columns)
+	for _, values := range entry.Rows {
+		row := Row{Values: make([]Value, len(values)), columns: columns}
+		for j, value := range values {
+			row.Values[j] = Value{raw: value.Raw, null: value.Null}
+			if j < len(entry.Types) {
+				row.Values[j].kind = entry.Types[j]
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// ExecContext only accepts USE, the session state does not matter as the
+// results are looked up by statement
+func (r replayQuerier) ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	if strings.HasPrefix(strings.ToUpper(stmt), "USE ") {
+		return 0, nil
+	}
+	return -1, errors.New("statements cannot be run on a replay")
+}
+
+func (r replayQuerier) Session(ctx context.Context) (*Session, error) {
+	return &Session{Querier: r, close: func() {}}, nil
+}
+
+func (r replayQuerier) PingContext(ctx context.Context) error {
+	return nil
+}
+
+func (r replayQuerier) Close() error {
+	return nil
+}
+
+func recordKey(stmt string, args []string) string {
+	return strings.Join(append([]string{stmt}, args...), "\x00")
+}
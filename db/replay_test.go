@@ -0,0 +1,88 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordReplay(t *testing.T) {
+	const top_queries = "select digest, exec_count from performance_schema.events_statements_summary_by_digest where LAST_SEEN >= now(6) - interval ? microsecond"
+	const locks = "select lock_summary from performance_schema.metadata_locks where owner_thread_id = ?"
+	file := filepath.Join(t.TempDir(), "incident.rec")
+	if err := OpenRecord(file, time.Second, []string{"db1"}); err != nil {
+		t.Fatal(err)
+	}
+	fake := NewFake(
+		Fixture{Statement: top_queries, Columns: []string{"digest", "exec_count"}, Rows: [][]interface{}{{"4f2a", 10}}},
+		Fixture{Statement: locks, Args: []interface{}{"48"}, Columns: []string{"lock_summary"}, Rows: [][]interface{}{{"orders"}}},
+		Fixture{Statement: locks, Args: []interface{}{"49"}, Columns: []string{"lock_summary"}, Rows: [][]interface{}{{"customers"}}},
+	)
+	mydb := Record(fake, "db1")
+	// the window of Top Queries is twice the interval
+	for _, stmt := range []struct {
+		stmt string
+		arg  interface{}
+	}{{top_queries, int64(2000000)}, {locks, "48"}, {locks, "49"}} {
+		if _, err := Query(mydb, stmt.stmt, stmt.arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake.Set(top_queries, []string{"digest", "exec_count"}, []interface{}{"4f2a", 25})
+	if _, err := Query(mydb, top_queries, int64(2000000)); err != nil {
+		t.Fatal(err)
+	}
+	CloseRecord()
+
+	replay, err := OpenReplay(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Interval != time.Second || !reflect.DeepEqual(replay.Servers, []string{"db1"}) {
+		t.Errorf("replay of %v %v, want 1s [db1]", replay.Interval, replay.Servers)
+	}
+	replay.TogglePause()
+	mydb = replay.Querier("db1")
+
+	exec_count := func(window int64) int64 {
+		t.Helper()
+		result, err := Query(mydb, top_queries, window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Rows) != 1 {
+			t.Fatalf("got %d rows, want 1", len(result.Rows))
+		}
+		count, _ := result.Rows[0].Get("exec_count").Int64()
+		return count
+	}
+	if count := exec_count(2000000); count != 10 {
+		t.Errorf("exec_count at the start is %d, want 10", count)
+	}
+	// played back with --interval 2s
+	if count := exec_count(4000000); count != 10 {
+		t.Errorf("exec_count with another window is %d, want 10", count)
+	}
+	replay.Seek(time.Hour)
+	if count := exec_count(2000000); count != 25 {
+		t.Errorf("exec_count at the end is %d, want 25", count)
+	}
+
+	// the arguments selecting the rows are not ignored
+	for thread_id, want := range map[string]string{"48": "orders", "49": "customers"} {
+		result, err := Query(mydb, locks, thread_id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Rows) != 1 || result.Rows[0].Get("lock_summary").String() != want {
+			t.Errorf("locks of %s are %v, want %s", thread_id, result.Strings(), want)
+		}
+	}
+	if _, err := Query(mydb, locks, "50"); err == nil {
+		t.Errorf("a thread not recorded was answered")
+	}
+	if _, err := Query(mydb, "select 1"); err == nil {
+		t.Errorf("a statement not recorded was answered")
+	}
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Value is a value of a result set. NULL is told apart from the empty
+// string with IsNull, the conversions return false for NULL or for a value
+// that does not fit the requested type.
+type Value struct {
+	raw  []byte
+	null bool
+	// kind is the database type name of the column
+	kind string
+}
+
+var time_layouts = []string{"2006-01-02 15:04:05.999999", "2006-01-02"}
+
+func (v Value) IsNull() bool {
+	return v.null
+}
+
+// Type returns the database type name of the column, like BIGINT or VARCHAR
+func (v Value) Type() string {
+	return v.kind
+}
+
+// String returns the value as sent by the server, NULL being empty
+func (v Value) String() string {
+	return string(v.raw)
+}
+
+func (v Value) Bytes() []byte {
+	return v.raw
+}
+
+func (v Value) Int64() (int64, bool) {
+	if v.null {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(string(v.raw), 10, 64)
+	return i, err == nil
+}
+
+func (v Value) Uint64() (uint64, bool) {
+	if v.null {
+		return 0, false
+	}
+	i, err := strconv.ParseUint(string(v.raw), 10, 64)
+	return i, err == nil
+}
+
+func (v Value) Float64() (float64, bool) {
+	if v.null {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(string(v.raw), 64)
+	return f, err == nil
+}
+
+// Decimal returns the exact value of a DECIMAL
+func (v Value) Decimal() (*big.Rat, bool) {
+	if v.null {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(v.raw))
+}
+
+// Time parses DATE, DATETIME and TIMESTAMP values as UTC
+func (v Value) Time() (time.Time, bool) {
+	if v.null {
+		return time.Time{}, false
+	}
+	for _, layout := range time_layouts {
+		if t, err := time.Parse(layout, string(v.raw)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Row gives access to the values of a row by position or by column name
+type Row struct {
+	Values  []Value
+	columns map[string]int
+}
+
+// Get returns the value of the column, NULL when there is no such column
+func (row Row) Get(name string) Value {
+	i, ok := row.columns[name]
+	if !ok {
+		return Value{null: true}
+	}
+	return row.Values[i]
+}
+
+// Result is a result set read by GetRows
+type Result struct {
+	Columns []string
+	Rows    []Row
+}
+
+// GetRows reads all the rows and closes them
+func GetRows(rows *sql.Rows) (*Result, error) {
+	defer rows.Close()
+
+	col_types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columns := columnIndex(cols)
+
+	result := &Result{Columns: cols}
+	raw_values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range raw_values {
+		dest[i] = &raw_values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := Row{Values: make([]Value, len(cols)), columns: columns}
+		for i, raw := range raw_values {
+			value := Value{kind: col_types[i].DatabaseTypeName()}
+			if raw == nil {
+				value.null = true
+			} else {
+				// RawBytes are only valid until the next Scan
+				value.raw = append([]byte{}, raw...)
+			}
+			row.Values[i] = value
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Strings is the string view of the result used to display tables, NULL
+// being an empty string.
+func (result *Result) Strings() [][]string {
+	var data [][]string
+	for _, row := range result.Rows {
+		line := make([]string, len(row.Values))
+		for i, value := range row.Values {
+			line[i] = value.String()
+		}
+		data = append(data, line)
+	}
+	return data
+}
+
+// NewResult builds a result from values, nil being NULL. It is used for the
+// results not coming from a server, like the ones of a Fake.
+func NewResult(cols []string, rows [][]interface{}) *Result {
+	columns := columnIndex(cols)
+	result := &Result{Columns: cols}
+	for _, values := range rows {
+		row := Row{Values: make([]Value, len(cols)), columns: columns}
+		for i := range row.Values {
+			if i >= len(values) || values[i] == nil {
+				row.Values[i] = Value{null: true}
+				continue
+			}
+			switch v := values[i].(type) {
+			case []byte:
+				row.Values[i] = Value{raw: v}
+			case float64:
+				row.Values[i] = Value{raw: []byte(strconv.FormatFloat(v, 'f', -1, 64))}
+			default:
+				row.Values[i] = Value{raw: []byte(fmt.Sprint(v))}
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
+
+// columnIndex maps the names of the columns to their position, the first
+// one wins when a name is repeated
+func columnIndex(cols []string) map[string]int {
+	columns := make(map[string]int, len(cols))
+	for i, col := range cols {
+		if _, ok := columns[col]; !ok {
+			columns[col] = i
+		}
+	}
+	return columns
+}
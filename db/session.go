@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Session is a dedicated connection for the statements depending on the
+// session state, like USE, so that this state never reaches the connections
+// of the pool used by the monitoring queries.
+type Session struct {
+	Querier
+	close func()
+}
+
+func NewSession(ctx context.Context, mydb Querier) (*Session, error) {
+	return mydb.Session(ctx)
+}
+
+// Use changes the default schema of the session
+func (session *Session) Use(ctx context.Context, schema string) error {
+	// sqlcheck:ignore USE does not take placeholders, the name is quoted
+	stmt := "USE `" + strings.ReplaceAll(schema, "`", "``") + "`"
+	return RunQueryContext(ctx, session, stmt)
+}
+
+// Query runs stmt and reads its result, the statement is canceled after
+// timeout unless it is 0.
+func (session *Session) Query(ctx context.Context, stmt string, timeout time.Duration) ([]string, [][]string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := QueryContext(ctx, session, stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Columns, result.Strings(), nil
+}
+
+// Close closes the connection instead of giving it back to the pool with
+// its session state.
+func (session *Session) Close() error {
+	session.close()
+	return nil
+}
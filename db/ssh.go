@@ -0,0 +1,270 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lefred/innotopgo/parse"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const ssh_default_port = "22"
+
+// sshTunnel is the SSH connection to a bastion, shared by all the MySQL
+// connections going through it. It is opened on the first dial and opened
+// again when it got closed.
+type sshTunnel struct {
+	mutex  sync.Mutex
+	addr   string
+	config *ssh.ClientConfig
+	client *ssh.Client
+}
+
+var (
+	tunnels_mutex sync.Mutex
+	tunnels       = map[string]*sshTunnel{}
+)
+
+// ssh_networks numbers the dial functions registered in the driver
+var ssh_networks int64
+
+// registerSSH registers in the driver a dial function going through the
+// bastion of cfg and returns its network name, unique to the server.
+func registerSSH(server string, cfg parse.Config) (string, error) {
+	tunnel, err := newSSHTunnel(cfg)
+	if err != nil {
+		return "", err
+	}
+	network := "tcp"
+	if len(cfg.Socket) > 0 {
+		network = "unix"
+	}
+	name := fmt.Sprintf("innotopgo-ssh-%s-%d", server, atomic.AddInt64(&ssh_networks, 1))
+	mysql.RegisterDialContext(name, func(ctx context.Context, addr string) (net.Conn, error) {
+		return tunnel.dial(ctx, network, addr)
+	})
+	return name, nil
+}
+
+func newSSHTunnel(cfg parse.Config) (*sshTunnel, error) {
+	ssh_user, addr, err := splitSSHTarget(cfg.SSH)
+	if err != nil {
+		return nil, err
+	}
+	key := ssh_user + "@" + addr + " " + cfg.SSHKey + " " + cfg.SSHKnownHosts
+
+	tunnels_mutex.Lock()
+	defer tunnels_mutex.Unlock()
+	if tunnel, ok := tunnels[key]; ok {
+		return tunnel, nil
+	}
+
+	auth, err := sshAuth(cfg.SSHKey)
+	if err != nil {
+		return nil, err
+	}
+	host_key_callback, err := sshHostKeyCallback(cfg.SSHKnownHosts)
+	if err != nil {
+		return nil, err
+	}
+	tunnel := &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            ssh_user,
+			Auth:            auth,
+			HostKeyCallback: host_key_callback,
+			Timeout:         10 * time.Second,
+		},
+	}
+	tunnels[key] = tunnel
+	return tunnel, nil
+}
+
+// splitSSHTarget splits [user@]host[:port], the user defaults to the login
+// name and the port to 22.
+func splitSSHTarget(target string) (string, string, error) {
+	ssh_user := ""
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		ssh_user, target = target[:i], target[i+1:]
+	}
+	if len(ssh_user) == 0 {
+		current, err := user.Current()
+		if err != nil {
+			return "", "", err
+		}
+		ssh_user = current.Username
+	}
+	host, port := target, ssh_default_port
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		host, port = h, p
+	}
+	host = strings.Trim(host, "[]")
+	if len(host) == 0 {
+		return "", "", fmt.Errorf("invalid ssh bastion '%s'", target)
+	}
+	return ssh_user, net.JoinHostPort(host, port), nil
+}
+
+// sshAuth returns the authentication methods to try: the given key, or the
+// SSH agent and the default keys not protected by a passphrase.
+func sshAuth(key_file string) ([]ssh.AuthMethod, error) {
+	var auth []ssh.AuthMethod
+	if len(key_file) > 0 {
+		signer, err := readSSHKey(key_file, true)
+		if err != nil {
+			return nil, err
+		}
+		return append(auth, ssh.PublicKeys(signer)), nil
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); len(sock) > 0 {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		var signers []ssh.Signer
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			if signer, err := readSSHKey(filepath.Join(home, ".ssh", name), false); err == nil {
+				signers = append(signers, signer)
+			}
+		}
+		if len(signers) > 0 {
+			auth = append(auth, ssh.PublicKeys(signers...))
+		}
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("no ssh key found, use --ssh-key or start an SSH agent")
+	}
+	return auth, nil
+}
+
+// readSSHKey reads a private key, the passphrase of an encrypted key is
+// asked only when prompt is set.
+func readSSHKey(file string, prompt bool) (ssh.Signer, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok && prompt {
+		passphrase, err := parse.ReadPassword(fmt.Sprintf("Enter passphrase for %s: ", file))
+		if err != nil {
+			return nil, err
+		}
+		return ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return signer, nil
+}
+
+func sshHostKeyCallback(known_hosts string) (ssh.HostKeyCallback, error) {
+	if len(known_hosts) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		known_hosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(known_hosts)
+	if err != nil {
+		return nil, err
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if key_err, ok := err.(*knownhosts.KeyError); ok && len(key_err.Want) == 0 {
+			return fmt.Errorf("host key of %s not found in %s, connect once with ssh to check and add it", hostname, known_hosts)
+		}
+		return err
+	}, nil
+}
+
+func (tunnel *sshTunnel) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	client, err := tunnel.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", tunnel.addr, err)
+	}
+	return conn, nil
+}
+
+func (tunnel *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+	if tunnel.client != nil {
+		return tunnel.client, nil
+	}
+
+	dialer := net.Dialer{Timeout: tunnel.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", tunnel.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", tunnel.addr, err)
+	}
+	config := *tunnel.config
+	config.HostKeyAlgorithms = tunnel.knownAlgorithms(conn.RemoteAddr())
+	// the timeout of the config only covers the dial, not the handshake
+	conn.SetDeadline(time.Now().Add(config.Timeout))
+	ssh_conn, chans, reqs, err := ssh.NewClientConn(conn, tunnel.addr, &config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh %s: %w", tunnel.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	client := ssh.NewClient(ssh_conn, chans, reqs)
+	tunnel.client = client
+	go func() {
+		client.Wait()
+		tunnel.mutex.Lock()
+		if tunnel.client == client {
+			tunnel.client = nil
+		}
+		tunnel.mutex.Unlock()
+	}()
+	return client, nil
+}
+
+// knownAlgorithms returns the types of the host keys known for the bastion,
+// otherwise the server could offer a key of another type and fail the check.
+func (tunnel *sshTunnel) knownAlgorithms(remote net.Addr) []string {
+	// a key that cannot match makes the callback list the known ones
+	err := tunnel.config.HostKeyCallback(tunnel.addr, remote, unknownKey{})
+	key_err, ok := err.(*knownhosts.KeyError)
+	if !ok {
+		return nil
+	}
+	var algorithms []string
+	for _, known := range key_err.Want {
+		switch known.Key.Type() {
+		case ssh.KeyAlgoRSA:
+			// keep the SHA-2 signatures preferred by the servers
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algorithms = append(algorithms, known.Key.Type())
+		}
+	}
+	return algorithms
+}
+
+type unknownKey struct{}
+
+func (unknownKey) Type() string                        { return "innotopgo-unknown" }
+func (unknownKey) Marshal() []byte                     { return []byte("innotopgo-unknown") }
+func (unknownKey) Verify([]byte, *ssh.Signature) error { return errors.New("unknown key") }
@@ -0,0 +1,319 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lefred/innotopgo/parse"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestSigner(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, key
+}
+
+// startSSHServer runs a bastion accepting the client key and forwarding the
+// direct-tcpip channels, it returns its address
+func startSSHServer(t *testing.T, host_key ssh.Signer, client_key ssh.PublicKey) string {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), client_key.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(host_key)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for new_channel := range chans {
+		if new_channel.ChannelType() != "direct-tcpip" {
+			new_channel.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(new_channel.ExtraData(), &target); err != nil {
+			new_channel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			new_channel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channel_reqs, err := new_channel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channel_reqs)
+		go func() {
+			io.Copy(channel, remote)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(remote, channel)
+			remote.Close()
+		}()
+	}
+}
+
+// startMySQLServer runs a server speaking enough of the MySQL protocol to
+// accept any login and answer the pings, it returns its address
+func startMySQLServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMySQL(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveMySQL(conn net.Conn) {
+	defer conn.Close()
+	const capabilities = 0x1 | 0x200 | 0x2000 | 0x8000 | 0x80000 // long password, 4.1, transactions, secure and plugin auth
+	var handshake bytes.Buffer
+	handshake.WriteByte(10)
+	handshake.WriteString("8.0.36\x00")
+	binary.Write(&handshake, binary.LittleEndian, uint32(1))
+	handshake.WriteString("abcdefgh\x00")
+	binary.Write(&handshake, binary.LittleEndian, uint16(capabilities&0xffff))
+	handshake.WriteByte(0x21)
+	binary.Write(&handshake, binary.LittleEndian, uint16(2))
+	binary.Write(&handshake, binary.LittleEndian, uint16(capabilities>>16))
+	handshake.WriteByte(21)
+	handshake.Write(make([]byte, 10))
+	handshake.WriteString("ijklmnopqrst\x00")
+	handshake.WriteString("mysql_native_password\x00")
+	if err := writeMySQLPacket(conn, 0, handshake.Bytes()); err != nil {
+		return
+	}
+	ok := []byte{0, 0, 0, 2, 0, 0, 0}
+	for {
+		seq, payload, err := readMySQLPacket(conn)
+		if err != nil || (len(payload) > 0 && payload[0] == 1 && seq == 0) {
+			// COM_QUIT
+			return
+		}
+		// the login and the pings are all answered with OK
+		if err := writeMySQLPacket(conn, seq+1, ok); err != nil {
+			return
+		}
+	}
+}
+
+func readMySQLPacket(conn net.Conn) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(conn, payload)
+	return header[3], payload, err
+}
+
+func writeMySQLPacket(conn net.Conn, seq byte, payload []byte) error {
+	size := len(payload)
+	_, err := conn.Write(append([]byte{byte(size), byte(size >> 8), byte(size >> 16), seq}, payload...))
+	return err
+}
+
+func writeKnownHosts(t *testing.T, addr string, key ssh.PublicKey) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	content := ""
+	if key != nil {
+		content = knownhosts.Line([]string{knownhosts.Normalize(addr)}, key) + "\n"
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func writeKeyFile(t *testing.T, key ed25519.PrivateKey) string {
+	t.Helper()
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// startAgent serves the keys on an SSH agent socket and points
+// SSH_AUTH_SOCK to it, HOME has no key
+func startAgent(t *testing.T, key ed25519.PrivateKey) {
+	t.Helper()
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestSSHTunnel(t *testing.T) {
+	host_key, _ := newTestSigner(t)
+	changed_key, _ := newTestSigner(t)
+	client_signer, client_key := newTestSigner(t)
+	_, other_key := newTestSigner(t)
+	mysql_addr := startMySQLServer(t)
+	mysql_host, mysql_port, _ := net.SplitHostPort(mysql_addr)
+
+	tests := []struct {
+		name string
+		// known is the host key in known_hosts, none when nil
+		known ssh.PublicKey
+		key   ed25519.PrivateKey
+		agent bool
+		err   string
+	}{
+		{name: "key file", known: host_key.PublicKey(), key: client_key},
+		{name: "agent", known: host_key.PublicKey(), key: client_key, agent: true},
+		{name: "unknown host key", key: client_key, err: "not found in"},
+		{name: "changed host key", known: changed_key.PublicKey(), key: client_key, err: "key mismatch"},
+		{name: "key not authorized", known: host_key.PublicKey(), key: other_key, err: "unable to authenticate"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ssh_addr := startSSHServer(t, host_key, client_signer.PublicKey())
+			cfg := parse.Config{
+				User: "app", Password: "secret", Host: mysql_host, Port: mysql_port,
+				SSLMode:       parse.SSLModeDisabled,
+				SSH:           "admin@" + ssh_addr,
+				SSHKnownHosts: writeKnownHosts(t, ssh_addr, test.known),
+			}
+			if test.agent {
+				startAgent(t, test.key)
+			} else {
+				cfg.SSHKey = writeKeyFile(t, test.key)
+			}
+			mydb, err := Connect("db1", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mydb.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err = mydb.PingContext(ctx)
+			if len(test.err) == 0 {
+				if err != nil {
+					t.Errorf("ping through the tunnel failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ping error is %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSplitSSHTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		user   string
+		addr   string
+	}{
+		{target: "admin@bastion", user: "admin", addr: "bastion:22"},
+		{target: "admin@bastion:2222", user: "admin", addr: "bastion:2222"},
+		{target: "admin@[::1]:2222", user: "admin", addr: "[::1]:2222"},
+		{target: "ops@corp@bastion", user: "ops@corp", addr: "bastion:22"},
+	}
+	for _, test := range tests {
+		ssh_user, addr, err := splitSSHTarget(test.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ssh_user != test.user || addr != test.addr {
+			t.Errorf("splitSSHTarget(%s) = %s, %s, want %s, %s", test.target, ssh_user, addr, test.user, test.addr)
+		}
+	}
+}
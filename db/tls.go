@@ -0,0 +1,108 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lefred/innotopgo/parse"
+)
+
+// tls_configs numbers the configurations registered in the driver
+var tls_configs int64
+
+// registerTLS registers the TLS configuration matching the ssl settings of
+// cfg in the driver and returns the value to use as tls parameter of the DSN.
+// Each server gets its own configuration, even when it shares its address
+// with another one reached through another bastion.
+func registerTLS(server string, cfg parse.Config) (string, error) {
+	switch cfg.SSLMode {
+	case parse.SSLModeDisabled:
+		return "false", nil
+	case parse.SSLModePreferred:
+		// the driver falls back to an unencrypted connection only
+		// with its own "preferred" configuration
+		return "preferred", nil
+	}
+	tls_config, err := tlsConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("innotopgo-%s-%d", server, atomic.AddInt64(&tls_configs, 1))
+	if err := mysql.RegisterTLSConfig(name, tls_config); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// tlsConfig returns the TLS configuration of the REQUIRED, VERIFY_CA and
+// VERIFY_IDENTITY modes
+func tlsConfig(cfg parse.Config) (*tls.Config, error) {
+	tls_config := &tls.Config{}
+
+	var pool *x509.CertPool
+	if len(cfg.SSLCA) > 0 {
+		pem, err := ioutil.ReadFile(cfg.SSLCA)
+		if err != nil {
+			return nil, err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ssl-ca file '%s'", cfg.SSLCA)
+		}
+	}
+
+	if len(cfg.SSLCert) > 0 {
+		cert, err := tls.LoadX509KeyPair(cfg.SSLCert, cfg.SSLKey)
+		if err != nil {
+			return nil, err
+		}
+		tls_config.Certificates = []tls.Certificate{cert}
+	}
+
+	switch cfg.SSLMode {
+	case parse.SSLModeRequired:
+		tls_config.InsecureSkipVerify = true
+	case parse.SSLModeVerifyCA:
+		// the chain is verified but not the host name, crypto/tls can only
+		// do this with our own verification function
+		tls_config.InsecureSkipVerify = true
+		tls_config.VerifyPeerCertificate = verifyChain(pool)
+	case parse.SSLModeVerifyIdentity:
+		tls_config.RootCAs = pool
+		tls_config.ServerName = cfg.SSLServerName
+		if len(tls_config.ServerName) == 0 {
+			tls_config.ServerName = cfg.Host
+		}
+	}
+	return tls_config, nil
+}
+
+func verifyChain(pool *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(raw_certs [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw_certs) == 0 {
+			return errors.New("the server did not present any certificate")
+		}
+		certs := make([]*x509.Certificate, len(raw_certs))
+		for i, raw := range raw_certs {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
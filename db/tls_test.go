@@ -0,0 +1,176 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lefred/innotopgo/parse"
+)
+
+// testCA is a self-signed certificate authority signing the certificates of
+// the test servers
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), name+".pem")
+	writePEM(t, file, "CERTIFICATE", der)
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue returns a server certificate for the host name signed by the CA
+func (ca *testCA) issue(t *testing.T, host string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writePEM(t *testing.T, file string, block_type string, der []byte) {
+	t.Helper()
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: block_type, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake runs a TLS handshake between the client configuration and a
+// server presenting cert
+func handshake(client_config *tls.Config, cert tls.Certificate) error {
+	client_conn, server_conn := net.Pipe()
+	defer client_conn.Close()
+	defer server_conn.Close()
+	server := tls.Server(server_conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+	go server.Handshake()
+	client_conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return tls.Client(client_conn, client_config).Handshake()
+}
+
+func TestTLSConfigVerification(t *testing.T) {
+	ca := newTestCA(t, "innotopgo-ca")
+	other_ca := newTestCA(t, "other-ca")
+	tests := []struct {
+		name        string
+		mode        string
+		host        string
+		server_name string
+		cert        tls.Certificate
+		ok          bool
+	}{
+		{name: "required accepts any certificate", mode: parse.SSLModeRequired, host: "10.0.0.1", cert: other_ca.issue(t, "db.example.com"), ok: true},
+		{name: "verify_ca accepts the CA", mode: parse.SSLModeVerifyCA, host: "10.0.0.1", cert: ca.issue(t, "db.example.com"), ok: true},
+		{name: "verify_ca rejects another CA", mode: parse.SSLModeVerifyCA, host: "db.example.com", cert: other_ca.issue(t, "db.example.com")},
+		{name: "verify_identity accepts the host", mode: parse.SSLModeVerifyIdentity, host: "db.example.com", cert: ca.issue(t, "db.example.com"), ok: true},
+		{name: "verify_identity rejects another host", mode: parse.SSLModeVerifyIdentity, host: "other.example.com", cert: ca.issue(t, "db.example.com")},
+		{name: "verify_identity checks ssl-server-name", mode: parse.SSLModeVerifyIdentity, host: "127.0.0.1", server_name: "db.example.com", cert: ca.issue(t, "db.example.com"), ok: true},
+		{name: "verify_identity rejects another CA", mode: parse.SSLModeVerifyIdentity, host: "db.example.com", cert: other_ca.issue(t, "db.example.com")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := parse.Config{Host: test.host, Port: "3306", SSLMode: test.mode, SSLCA: ca.file, SSLServerName: test.server_name}
+			tls_config, err := tlsConfig(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = handshake(tls_config, test.cert)
+			if test.ok && err != nil {
+				t.Errorf("handshake failed: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Errorf("handshake succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestTLSConfigInvalidCA(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(file, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := tlsConfig(parse.Config{SSLMode: parse.SSLModeVerifyCA, SSLCA: file})
+	if err == nil {
+		t.Errorf("tlsConfig accepted an ssl-ca without certificate")
+	}
+}
+
+func TestRegisterTLS(t *testing.T) {
+	ca := newTestCA(t, "innotopgo-ca")
+	for _, mode := range []string{parse.SSLModeDisabled, parse.SSLModePreferred} {
+		name, err := registerTLS("db1", parse.Config{SSLMode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{parse.SSLModeDisabled: "false", parse.SSLModePreferred: "preferred"}[mode]; name != want {
+			t.Errorf("registerTLS(%s) = %s, want %s", mode, name, want)
+		}
+	}
+	// two servers with the same address behind different bastions
+	cfg := parse.Config{Host: "10.0.0.1", Port: "3306", SSLMode: parse.SSLModeVerifyCA, SSLCA: ca.file}
+	first, err := registerTLS("db1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SSLMode = parse.SSLModeRequired
+	second, err := registerTLS("db2", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := registerTLS("db1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || first == again {
+		t.Errorf("registerTLS reused a name: %s, %s, %s", first, second, again)
+	}
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// trace_history is the number of statements kept for LastTraces
+const trace_history = 200
+
+// Trace is a statement run by innotopgo. Duration includes the time spent
+// reading the rows, Rows is -1 when they were not read.
+type Trace struct {
+	Start    time.Time
+	Duration time.Duration
+	Rows     int64
+	Err      error
+	Screen   string
+	Stmt     string
+}
+
+// Cost is the time spent by the statements run and their number
+type Cost struct {
+	Duration time.Duration
+	Queries  int
+}
+
+// Sub returns the cost of the statements run since prev
+func (cost Cost) Sub(prev Cost) Cost {
+	return Cost{Duration: cost.Duration - prev.Duration, Queries: cost.Queries - prev.Queries}
+}
+
+// CostQuerier counts the cost of the statements run through a Querier, the
+// statements of the sessions it opens are not counted
+type CostQuerier struct {
+	Querier
+	mutex sync.Mutex
+	cost  Cost
+}
+
+func NewCostQuerier(mydb Querier) *CostQuerier {
+	return &CostQuerier{Querier: mydb}
+}
+
+// Cost returns the cost of all the statements run so far
+func (q *CostQuerier) Cost() Cost {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.cost
+}
+
+func (q *CostQuerier) add(start time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.cost.Duration += time.Since(start)
+	q.cost.Queries++
+}
+
+func (q *CostQuerier) QueryContext(ctx context.Context, stmt string, args ...interface{}) (*Result, error) {
+	defer q.add(time.Now())
+	return q.Querier.QueryContext(ctx, stmt, args...)
+}
+
+func (q *CostQuerier) ExecContext(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	defer q.add(time.Now())
+	return q.Querier.ExecContext(ctx, stmt, args...)
+}
+
+var tracer struct {
+	mutex  sync.Mutex
+	out    io.WriteCloser
+	screen func() string
+	last   []Trace
+	next   int
+}
+
+// OpenTrace appends every statement run from now on to file
+func OpenTrace(file string) error {
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	tracer.mutex.Lock()
+	tracer.out = out
+	tracer.mutex.Unlock()
+	return nil
+}
+
+func CloseTrace() {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	if tracer.out != nil {
+		tracer.out.Close()
+		tracer.out = nil
+	}
+}
+
+// SetTraceScreen sets the function returning the screen issuing the
+// statements
+func SetTraceScreen(screen func() string) {
+	tracer.mutex.Lock()
+	tracer.screen = screen
+	tracer.mutex.Unlock()
+}
+
+// LastTraces returns the last statements run, the most recent first
+func LastTraces() []Trace {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	traces := make([]Trace, 0, len(tracer.last))
+	for i := 1; i <= len(tracer.last); i++ {
+		traces = append(traces, tracer.last[(tracer.next-i+len(tracer.last))%len(tracer.last)])
+	}
+	return traces
+}
+
+// currentScreen returns the screen issuing the statements
+func currentScreen() string {
+	tracer.mutex.Lock()
+	screen := tracer.screen
+	tracer.mutex.Unlock()
+	if screen == nil {
+		return "-"
+	}
+	return screen()
+}
+
+func startTrace(stmt string) *Trace {
+	return &Trace{Start: time.Now(), Rows: -1, Screen: currentScreen(), Stmt: stmt}
+}
+
+func endTrace(trace *Trace, count int64, err error) {
+	trace.Duration = time.Since(trace.Start)
+	trace.Rows = count
+	trace.Err = err
+
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	if len(tracer.last) < trace_history {
+		tracer.last = append(tracer.last, *trace)
+	} else {
+		tracer.last[tracer.next] = *trace
+	}
+	tracer.next = (tracer.next + 1) % trace_history
+	if tracer.out != nil {
+		fmt.Fprintln(tracer.out, trace.String())
+	}
+}
+
+// String formats the trace as a line of the trace file
+func (trace Trace) String() string {
+	line := fmt.Sprintf("%s screen=%s duration=%s rows=%d",
+		trace.Start.Format("2006-01-02T15:04:05.000000Z07:00"), trace.Screen, trace.Duration, trace.Rows)
+	if trace.Err != nil {
+		line = line + fmt.Sprintf(" error=%q", trace.Err.Error())
+	}
+	return line + " statement=" + trace.Statement()
+}
+
+// Statement returns the statement on a single line
+func (trace Trace) Statement() string {
+	return normalizeStatement(trace.Stmt)
+}
@@ -1,17 +1,22 @@
 module github.com/lefred/innotopgo
 
-go 1.15
+go 1.18
 
 require (
-	github.com/adam-lavrik/go-imath v0.0.0-20210910152346-265a42a96f0b // indirect
 	github.com/alexeyco/simpletable v0.0.0-20200730140406-5bb24159ccfb
 	github.com/elliotchance/orderedmap v1.4.0
 	github.com/go-sql-driver/mysql v1.6.0
+	github.com/mum4k/termdash v0.15.0
+	golang.org/x/crypto v0.17.0
+	golang.org/x/term v0.15.0
+)
+
+require (
+	github.com/gdamore/encoding v1.0.0 // indirect
+	github.com/gdamore/tcell/v2 v2.2.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.12 // indirect
-	github.com/mum4k/termdash v0.15.0
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20210309074719-68d13333faf2 // indirect
-	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d // indirect
-	golang.org/x/text v0.3.5 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -1,7 +1,6 @@
 package innotop
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 
@@ -12,8 +11,8 @@ import (
 	"github.com/mum4k/termdash/widgets/text"
 )
 
-func GetDetailsByThreadId(mydb *sql.DB, thread_id string) ([]string, [][]string, error) {
-	stmt := fmt.Sprintf(`select
+func GetDetailsByThreadId(mydb db.Querier, thread_id string) ([]string, [][]string, error) {
+	stmt := `select
 							pps.THREAD_ID, TYPE, pps.PROCESSLIST_ID, pps.PROCESSLIST_COMMAND,
 							pps.PROCESSLIST_STATE, pps.PARENT_THREAD_ID,
 							pps.INSTRUMENTED, pps.HISTORY,
@@ -49,19 +48,15 @@ func GetDetailsByThreadId(mydb *sql.DB, thread_id string) ([]string, [][]string,
 									   and (conattr_progname.ATTR_NAME = 'program_name'))))
 								  where pps.PROCESSLIST_ID is not null
 								       and pps.PROCESSLIST_COMMAND <> 'Daemon'
-									   and pps.THREAD_ID=%s;`, thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+									   and pps.THREAD_ID=?`
+	cols, data, err := db.GetData(mydb, stmt, thread_id)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func DisplayThreadDetails(mydb *sql.DB, c *container.Container, thread_id string) error {
+func DisplayThreadDetails(mydb db.Querier, c *container.Container, thread_id string) error {
 	details_window, err := text.New()
 	if err != nil {
 		return err
@@ -79,7 +74,7 @@ func DisplayThreadDetails(mydb *sql.DB, c *container.Container, thread_id string
 	c.Update("bottom_container", container.Clear())
 	c.Update("main_container", container.Focused())
 	c.Update("main_container", container.Focused())
-	c.Update("main_container", container.BorderTitle("Thread Details (<-- <Backspace> to return to Processlist)"))
+	c.Update("main_container", container.BorderTitle("Thread Details (<-- <Backspace> to return to Processlist  -  <v> to view the full statement)"))
 	details_window.Write("\n\n... please wait...", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
 	cols, data, err := GetDetailsByThreadId(mydb, thread_id)
 	if err != nil {
@@ -2,6 +2,7 @@ package innotop
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -104,6 +105,26 @@ func FormatBytes(b int) string {
 		float64(b)/float64(div), "KMGTPE"[exp])
 }
 
+// FormatPico formats a latency of performance_schema, in picoseconds, like
+// format_pico_time()
+func FormatPico(ps float64) string {
+	switch {
+	case ps >= 3_600_000_000_000_000:
+		return fmt.Sprintf("%.2f h", ps/3_600_000_000_000_000)
+	case ps >= 60_000_000_000_000:
+		return fmt.Sprintf("%.2f min", ps/60_000_000_000_000)
+	case ps >= 1_000_000_000_000:
+		return fmt.Sprintf("%.2f s", ps/1_000_000_000_000)
+	case ps >= 1_000_000_000:
+		return fmt.Sprintf("%.2f ms", ps/1_000_000_000)
+	case ps >= 1_000_000:
+		return fmt.Sprintf("%.2f us", ps/1_000_000)
+	case ps >= 1_000:
+		return fmt.Sprintf("%.2f ns", ps/1_000)
+	}
+	return fmt.Sprintf("%.0f ps", ps)
+}
+
 func GetValue(preview map[string]string, actual map[string]string, str string, negative ...bool) int {
 	print_negative := false
 	if len(negative) > 0 {
@@ -128,3 +149,57 @@ func GetValue(preview map[string]string, actual map[string]string, str string, n
 	}
 	return 0
 }
+
+// monitor_cost_warning is the percentage of the refresh interval spent by
+// the statements of a refresh from which the monitor cost is highlighted
+const monitor_cost_warning = 80
+
+// DisplayHeader writes the header bar with the current server and, when
+// several servers are monitored, its position in the list.
+func DisplayHeader(header *text.Text, servers *Servers) {
+	srv := servers.Current()
+	header.Reset()
+	header.Write("Inno", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
+	header.Write("Top", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+	header.Write(" Go ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
+	header.Write(version(), text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+	header.Write(" | ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
+	if servers.Len() > 1 {
+		line := fmt.Sprintf("%s (%d/%d) | ", srv.Name, servers.Position(), servers.Len())
+		header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
+	}
+	line := fmt.Sprintf("%s %s ", srv.Brand, srv.Version)
+	header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Italic()))
+	if len(srv.Socket) > 0 {
+		line = fmt.Sprintf("[%s:%s]", srv.Hostname, srv.Socket)
+	} else {
+		line = fmt.Sprintf("[%s:%s]", srv.Hostname, srv.Port)
+	}
+	header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Italic()))
+	if srv.Replay != nil {
+		now, speed, paused := srv.Replay.Status()
+		line = fmt.Sprintf(" REPLAY %s x%v ", now.Format("2006-01-02 15:04:05"), speed)
+		if paused {
+			line = fmt.Sprintf(" REPLAY %s PAUSED ", now.Format("2006-01-02 15:04:05"))
+		}
+		header.Write(" ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7))))
+		header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(31)), cell.FgColor(cell.ColorWhite), cell.Bold()))
+	} else if lost, retry_in := srv.Disconnected(); lost {
+		line = " DISCONNECTED – retrying now "
+		if seconds := int(math.Ceil(retry_in.Seconds())); seconds > 0 {
+			line = fmt.Sprintf(" DISCONNECTED – retrying in %ds ", seconds)
+		}
+		header.Write(" ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7))))
+		header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorRed), cell.FgColor(cell.ColorWhite), cell.Bold()))
+	} else if cost := servers.Cost(); cost.Queries > 0 {
+		header.Write(" | ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
+		line = fmt.Sprintf("monitor cost: %.1fms/refresh, %d queries", float64(cost.Duration.Microseconds())/1000, cost.Queries)
+		if servers.Interval > 0 && cost.Duration*100 >= servers.Interval*monitor_cost_warning {
+			line = fmt.Sprintf(" %s, close to the %v interval ", line, servers.Interval)
+			header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(172)), cell.FgColor(cell.ColorWhite), cell.Bold()))
+		} else {
+			header.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31))))
+		}
+	}
+	header.Write(strings.Repeat(" ", 200), text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7))))
+}
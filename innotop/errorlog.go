@@ -2,7 +2,6 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
@@ -38,29 +37,32 @@ func refresh_errorlog_info(t *tcell.Terminal, cancel context.CancelFunc, ctx con
 	}
 }
 
-func GetErrorLog(mydb *sql.DB, choices_prio_info *orderedmap.OrderedMap, choices_sub_info *orderedmap.OrderedMap) ([]string, [][]string, error) {
+func GetErrorLog(mydb db.Querier, choices_prio_info *orderedmap.OrderedMap, choices_sub_info *orderedmap.OrderedMap) ([]string, [][]string, error) {
+	var args []interface{}
 	prio_string := ""
 	for _, key := range choices_prio_info.Keys() {
 		element, _ := choices_prio_info.Get(key)
 		if element == false {
-			prio_string = prio_string + ",'" + fmt.Sprintf("%v", key) + "'"
+			prio_string = prio_string + ",?"
+			args = append(args, key)
 		}
 	}
 	prio_string_query := ""
 	if len(prio_string) > 0 {
-		prio_string_query = fmt.Sprintf("prio NOT IN (%v)", prio_string[1:])
+		prio_string_query = "prio NOT IN (" + prio_string[1:] + ")"
 	}
 
 	sub_string := ""
 	for _, key := range choices_sub_info.Keys() {
 		element, _ := choices_sub_info.Get(key)
 		if element == false {
-			sub_string = sub_string + ",'" + fmt.Sprintf("%v", key) + "'"
+			sub_string = sub_string + ",?"
+			args = append(args, key)
 		}
 	}
 	sub_string_query := ""
 	if len(sub_string) > 0 {
-		sub_string_query = fmt.Sprintf("subsystem NOT IN (%v)", sub_string[1:])
+		sub_string_query = "subsystem NOT IN (" + sub_string[1:] + ")"
 	}
 
 	if len(prio_string_query) > 0 {
@@ -76,19 +78,14 @@ func GetErrorLog(mydb *sql.DB, choices_prio_info *orderedmap.OrderedMap, choices
 
 	stmt := fmt.Sprintf("SELECT *, cast(unix_timestamp(logged)*1000000 as unsigned) logged_int FROM performance_schema.error_log %v %v ORDER BY logged", prio_string_query, sub_string_query)
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt, args...)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func DisplayErrorlog(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (keyboard.Key, error) {
+func DisplayErrorlog(servers *Servers, c *container.Container, t *tcell.Terminal, interval time.Duration) (keyboard.Key, error) {
 	ctxmem, cancel := context.WithCancel(context.Background())
 	k := keyboard.KeyBackspace2
 	prio_window, err := text.New()
@@ -127,8 +124,18 @@ func DisplayErrorlog(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (k
 
 	reset_window := true
 	last_logged := 0
+	var last_server *Server
 
-	go refresh_errorlog_info(t, cancel, ctxmem, 1*time.Second, func() error {
+	go refresh_errorlog_info(t, cancel, ctxmem, interval, servers.Refresh(func(srv *Server) error {
+		if len(srv.Unavailable(ScreenErrorlog)) > 0 {
+			// the quitter goes back to the processlist
+			return nil
+		}
+		if srv != last_server {
+			reset_window = true
+			last_server = srv
+		}
+		mydb := srv.DB
 		choices_window.Reset()
 		for _, key := range choices_prio_info.Keys() {
 			element_p := " "
@@ -155,7 +162,6 @@ func DisplayErrorlog(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (k
 
 		_, data, err := GetErrorLog(mydb, choices_prio_info, choices_sub_info)
 		if err != nil {
-			cancel()
 			return err
 		}
 		for _, row := range data {
@@ -174,7 +180,7 @@ func DisplayErrorlog(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (k
 			}
 		}
 		return nil
-	})
+	}))
 
 	systemB, err := button.New("(s)ystem", func() error {
 		value, _ := choices_prio_info.Get("system")
@@ -394,6 +400,13 @@ func DisplayErrorlog(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (k
 			k = k2.Key
 			cancel()
 			return
+		} else if servers.HandleKey(k2) {
+			if len(servers.Current().Unavailable(ScreenErrorlog)) > 0 {
+				// the processlist tells why
+				k = keyboard.KeyBackspace2
+				cancel()
+			}
+			return
 		} else {
 			return
 		}
@@ -0,0 +1,65 @@
+package innotop
+
+import (
+	"testing"
+
+	"github.com/elliotchance/orderedmap"
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestGetErrorLog(t *testing.T) {
+	const select_error_log = "SELECT *, cast(unix_timestamp(logged)*1000000 as unsigned) logged_int FROM performance_schema.error_log"
+	cols := []string{"logged", "thread_id", "prio", "error_code", "subsystem", "data", "logged_int"}
+	tests := []struct {
+		name string
+		// hidden are the priorities and the subsystems unchecked
+		hidden_prio []string
+		hidden_sub  []string
+		stmt        string
+		args        []interface{}
+	}{
+		{name: "everything", stmt: select_error_log + " ORDER BY logged"},
+		{name: "priorities", hidden_prio: []string{"note", "warning"},
+			stmt: select_error_log + " WHERE prio NOT IN (?,?) ORDER BY logged", args: []interface{}{"note", "warning"}},
+		{name: "subsystems", hidden_sub: []string{"innodb"},
+			stmt: select_error_log + " WHERE subsystem NOT IN (?) ORDER BY logged", args: []interface{}{"innodb"}},
+		{name: "both", hidden_prio: []string{"system"}, hidden_sub: []string{"server", "repl"},
+			stmt: select_error_log + " WHERE prio NOT IN (?) AND subsystem NOT IN (?,?) ORDER BY logged",
+			args: []interface{}{"system", "server", "repl"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			choices_prio_info := orderedmap.NewOrderedMap()
+			for _, prio := range []string{"system", "note", "warning", "error"} {
+				choices_prio_info.Set(prio, true)
+			}
+			for _, prio := range test.hidden_prio {
+				choices_prio_info.Set(prio, false)
+			}
+			choices_sub_info := orderedmap.NewOrderedMap()
+			for _, sub := range []string{"server", "innodb", "repl"} {
+				choices_sub_info.Set(sub, true)
+			}
+			for _, sub := range test.hidden_sub {
+				choices_sub_info.Set(sub, false)
+			}
+
+			// only the statement with the expected arguments has rows
+			fake := db.NewFake(db.Fixture{Statement: test.stmt, Args: test.args, Columns: cols,
+				Rows: [][]interface{}{{"2024-05-02 10:00:00.000000", 0, "Error", "MY-010119", "Server", "Aborting", 1714644000000000}}})
+			if len(test.args) > 0 {
+				fake.Add(db.Fixture{Statement: test.stmt, Columns: cols})
+			}
+			got_cols, data, err := GetErrorLog(fake, choices_prio_info, choices_sub_info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got_cols) != len(cols) || len(data) != 1 {
+				t.Fatalf("got %v %q, want one row", got_cols, data)
+			}
+			if data[0][5] != "Aborting" || data[0][6] != "1714644000000000" {
+				t.Errorf("row is %q", data[0])
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lefred/innotopgo/db"
 	"github.com/mum4k/termdash/cell"
@@ -14,13 +14,13 @@ import (
 	"github.com/mum4k/termdash/widgets/text"
 )
 
-func GetQueryByThreadId(mydb *sql.DB, thread_id string) (string, string, error) {
-	stmt := fmt.Sprintf("select db, current_statement from sys.x$processlist where thd_id=%s;", thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return "", "", err
-	}
-	_, data, err := db.GetData(rows)
+// explain_analyze_timeout aborts the EXPLAIN ANALYZE of long queries, use
+// ANALYZE /*NO_TIMEOUT*/ to wait until the end
+const explain_analyze_timeout = 10 * time.Second
+
+func GetQueryByThreadId(mydb db.Querier, thread_id string) (string, string, error) {
+	stmt := "select db, current_statement from sys.x$processlist where thd_id=?"
+	_, data, err := db.GetData(mydb, stmt, thread_id)
 	if err != nil {
 		return "", "", err
 	}
@@ -38,46 +38,45 @@ func GetQueryByThreadId(mydb *sql.DB, thread_id string) (string, string, error)
 	return query_db, query_text, err
 }
 
-func GetExplain(ctx context.Context, mydb *sql.DB, explain_type string, query_db string, query_test string) ([]string, [][]string, error) {
-	mydb.SetMaxOpenConns(1)
-	var rows *sql.Rows
-	var err error
+// GetExplain runs the EXPLAIN on its own session, the default schema of the
+// query must not leak into the monitoring connections.
+func GetExplain(ctx context.Context, mydb db.Querier, explain_type string, query_db string, query_test string) ([]string, [][]string, error) {
+	session, err := db.NewSession(ctx, mydb)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer session.Close()
 
 	if len(query_db) > 0 {
-		_, err := mydb.Exec("USE " + query_db)
-		if err != nil {
+		if err := session.Use(ctx, query_db); err != nil {
 			return nil, nil, err
 		}
 	}
+	timeout := time.Duration(0)
+	if explain_type == "ANALYZE" {
+		timeout = explain_analyze_timeout
+	}
 	if explain_type == "NORMAL" {
 		explain_type = ""
 	}
+	// sqlcheck:ignore the query text is the statement to explain
 	stmt := fmt.Sprintf("EXPLAIN %s %s", explain_type, query_test)
-	if explain_type == "ANALYZE" {
-		rows, err = db.QueryTimeout(ctx, mydb, stmt)
-
-	} else {
-		rows, err = db.Query(mydb, stmt)
-	}
-	if err != nil {
-		return nil, nil, err
-	}
+	return session.Query(ctx, stmt, timeout)
+}
 
-	cols, data, err := db.GetData(rows)
+func DisplayExplain(ctx context.Context, mydb db.Querier, c *container.Container, top_window *text.Text, main_window *text.Text, thread_id string, explain_type string) error {
+	query_db, query_text, err := GetQueryByThreadId(mydb, thread_id)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
-	mydb.SetMaxOpenConns(0)
-	return cols, data, err
+	return DisplayExplainQuery(ctx, mydb, c, top_window, main_window, query_db, query_text, explain_type)
 }
 
-func DisplayExplain(ctx context.Context, mydb *sql.DB, c *container.Container, top_window *text.Text, main_window *text.Text, thread_id string, explain_type string) error {
+// DisplayExplainQuery shows the plan of a query run with the default schema
+// query_db, like the sample query of a statement digest
+func DisplayExplainQuery(ctx context.Context, mydb db.Querier, c *container.Container, top_window *text.Text, main_window *text.Text, query_db string, query_text string, explain_type string) error {
 	var line string
 	var err error
-	query_db, query_text, err := GetQueryByThreadId(mydb, thread_id)
-	if err != nil {
-		return err
-	}
 	if strings.HasPrefix(explain_type, "ANALYZE") {
 		c.Update("main_container", container.BorderTitle("EXPLAIN ANALYZE (<-- <Backspace> to return)"))
 		if explain_type == "ANALYZE" {
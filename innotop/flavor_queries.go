@@ -0,0 +1,123 @@
+package innotop
+
+import (
+	"strings"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// querySet holds the statements whose syntax depends on the flavor and the
+// version of the server. Their columns are renamed by normalizeColumns to
+// the names used by MySQL 8.0.22 and later.
+type querySet struct {
+	replica_status string
+	replicas       string
+	// processlist is used when the performance_schema one cannot be
+	processlist string
+}
+
+var mysql80_queries = querySet{
+	replica_status: `SHOW REPLICA STATUS`,
+	replicas:       `SHOW REPLICAS`,
+	processlist:    mysql_processlist,
+}
+
+var mysql57_queries = querySet{
+	replica_status: `SHOW SLAVE STATUS`,
+	replicas:       `SHOW SLAVE HOSTS`,
+	processlist:    mysql_processlist,
+}
+
+var mariadb_queries = querySet{
+	replica_status: `SHOW ALL SLAVES STATUS`,
+	replicas:       `SHOW SLAVE HOSTS`,
+	processlist: `select COMMAND AS command, NULL AS thd_id, ID AS conn_id, NULL AS pid, STATE AS state,
+                         concat(USER,'@',HOST) AS user, DB AS db, INFO AS current_statement,
+                         if(COMMAND = 'Sleep', NULL, concat(round(TIME_MS / 1000, 2), ' s')) AS statement_latency,
+                         NULL AS lock_latency,
+                         if(COMMAND = 'Sleep', 0, round(TIME_MS * 1000000000)) AS sort_time,
+                         NULL AS lock_time, EXAMINED_ROWS AS rows_examined, MEMORY_USED AS memory, NULL AS digest,
+                         ` + processlist_thread_type + ` AS thread_type, ID = connection_id() AS own, INFO AS full_statement
+                    from information_schema.PROCESSLIST
+                   where COMMAND <> 'Daemon'
+                   order by sort_time desc`,
+}
+
+// processlist_thread_type tells the replication threads, the background
+// threads and the client connections apart in information_schema
+const processlist_thread_type = `case when COMMAND like 'Binlog Dump%' or COMMAND like 'Slave%' or USER = 'system user' then 'replication'
+                              when USER = 'event_scheduler' then 'background'
+                              else 'foreground' end`
+
+const mysql_processlist = `select COMMAND AS command, NULL AS thd_id, ID AS conn_id, NULL AS pid, STATE AS state,
+                         concat(USER,'@',HOST) AS user, DB AS db, INFO AS current_statement,
+                         if(COMMAND = 'Sleep', NULL, concat(TIME, ' s')) AS statement_latency,
+                         NULL AS lock_latency,
+                         if(COMMAND = 'Sleep', 0, TIME * 1000000000000) AS sort_time,
+                         NULL AS lock_time, NULL AS rows_examined, NULL AS memory, NULL AS digest,
+                         ` + processlist_thread_type + ` AS thread_type, ID = connection_id() AS own, INFO AS full_statement
+                    from information_schema.PROCESSLIST
+                   where COMMAND <> 'Daemon'
+                   order by sort_time desc`
+
+func queries(caps db.Capabilities) querySet {
+	switch {
+	case caps.MariaDB:
+		return mariadb_queries
+	case caps.ReplicaStatements():
+		return mysql80_queries
+	}
+	return mysql57_queries
+}
+
+// column_names are the columns not following the Master/Slave to
+// Source/Replica renaming
+var column_names = map[string]string{
+	"Connection_name":       "Channel_Name",
+	"Server_id":             "Server_Id",
+	"Master_id":             "Source_Id",
+	"Slave_UUID":            "Replica_UUID",
+	"Gtid_IO_Pos":           "Retrieved_Gtid_Set",
+	"Gtid_Slave_Pos":        "Executed_Gtid_Set",
+	"Get_master_public_key": "Get_Source_public_key",
+}
+
+var column_words = strings.NewReplacer("Master", "Source", "Slave", "Replica")
+
+func normalizeColumns(cols []string) []string {
+	normalized := make([]string, len(cols))
+	for i, col := range cols {
+		if name, ok := column_names[col]; ok {
+			normalized[i] = name
+		} else {
+			normalized[i] = column_words.Replace(col)
+		}
+	}
+	return normalized
+}
+
+// normalizeReplicaStatus adds the Auto_Position column to the MariaDB
+// replica status, GTID replication being enabled with Using_Gtid there.
+func normalizeReplicaStatus(cols []string, data [][]string) ([]string, [][]string) {
+	using_gtid := -1
+	for i, col := range cols {
+		if col == "Auto_Position" {
+			return cols, data
+		}
+		if col == "Using_Gtid" {
+			using_gtid = i
+		}
+	}
+	if using_gtid < 0 {
+		return cols, data
+	}
+	cols = append(cols, "Auto_Position")
+	for i, row := range data {
+		auto_position := "1"
+		if strings.EqualFold(row[using_gtid], "No") {
+			auto_position = "0"
+		}
+		data[i] = append(row, auto_position)
+	}
+	return cols, data
+}
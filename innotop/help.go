@@ -31,18 +31,45 @@ func DisplayHelp(c *container.Container) error {
 	help_window.Write(" Main keys (available in all sections)       Help Screen (?)\n")
 	help_window.Write(" -------------------------------------       ---------------\n\n")
 	help_window.Write(" <ESC> : quit InnoTop Go any time             <backspace> : return to processlist\n")
-	help_window.Write(" <?>   : get this screen\n\n")
+	help_window.Write(" <?>   : get this screen\n")
+	help_window.Write(" <Tab> : switch to the next server\n")
+	help_window.Write(" <1-9> : switch to the server by position\n\n")
 	help_window.Write(" Processlist Screen                           Query Execution Plan Screen (e)\n")
 	help_window.Write(" ------------------                           -------------------------------\n\n")
 	help_window.Write(" <spacebar> : refresh processlist                        <backspace> : return to processlist\n")
-	help_window.Write(" <D>        : get details of the thread                  <spacebar>  : change format of QEP\n")
+	help_window.Write(" <arrows>   : select a thread, also <PgUp> <PgDn> and the mouse\n")
+	help_window.Write(" <D>        : get details of the selected thread         <spacebar>  : change format of QEP\n")
 	help_window.Write(" <e>        : go to Query Execution Plan                                (normal, tree, json)\n")
-	help_window.Write(" <K>        : kill a query                               <a>         : run EXPLAIN ANALYZE (timeout after 5min)\n")
+	help_window.Write(" <k>        : kill the selected thread                   <a>         : run EXPLAIN ANALYZE (timeout after 5min)\n")
 	help_window.Write(" <I>        : get InnoDB info                            <A>         : run EXPLAIN ANALYZE (no timeout)\n")
 	help_window.Write(" <M>        : get Memory info                 <mouse and arrow keys> : change the focus on section\n")
 	help_window.Write(" <E>        : get Error Log Dashboard                                  and browse using the arrow keys\n")
-	help_window.Write(" <L>        : get Locking info\n")
+	help_window.Write(" <L>        : get Locking info of the selected thread\n")
+	help_window.Write(" <v>        : view the full statement of the selected thread, also from the details\n")
+	help_window.Write(" <K>        : kill all the threads matching the filter\n")
 	help_window.Write(" <R>        : get Replication info\n")
+	help_window.Write(" <S>        : show the last statements run by innotopgo\n")
+	help_window.Write(" <T>        : get Top Queries, the statement digests run during the last interval\n")
+	help_window.Write(" <<> <>>    : sort on the previous or next column\n")
+	help_window.Write(" <r>        : reverse the sort order\n")
+	help_window.Write(" <F>        : filter the threads, like user=app cmd!=Sleep stmt~/FOR UPDATE/ time>5s\n")
+	help_window.Write(" <i> <b>    : hide or show the idle connections, the background threads\n")
+	help_window.Write(" <o> <c>    : hide or show the connections of innotopgo, the replication threads\n")
+	help_window.Write("              <i> and <r> no longer open InnoDB and Replication here, use <I> and <R>\n")
+	help_window.Write(" <g>        : group the threads by user, host, db, command, state, statement or not\n")
+	help_window.Write("              <d> shows the threads of the selected group, <backspace> the groups again\n\n")
+	help_window.Write(" Top Queries Screen (T)\n")
+	help_window.Write(" ----------------------\n\n")
+	help_window.Write(" <arrows> : select a digest                   <<> <>> <r> : sort on a delta, reverse the order\n")
+	help_window.Write(" <e>      : EXPLAIN the sample query of the selected digest, <backspace> returns to Top Queries\n\n")
+	help_window.Write(" Statement Viewer (v)\n")
+	help_window.Write(" --------------------\n\n")
+	help_window.Write(" <arrows> : scroll the statement, also <PgUp> <PgDn> and the mouse wheel\n")
+	help_window.Write(" <f>      : show the formatted or the original text     <c> : copy it to the clipboard (OSC 52)\n\n")
+	help_window.Write(" Replay (--replay)\n")
+	help_window.Write(" -----------------\n\n")
+	help_window.Write(" <p>     : pause or resume the playback       <+> <-> : play faster or slower\n")
+	help_window.Write(" <.> <,> : step forward or backward           <]> <[> : seek one minute forward or backward\n")
 
 	return nil
 }
@@ -2,7 +2,6 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
@@ -35,7 +34,7 @@ func refresh_innodb_info(t *tcell.Terminal, cancel context.CancelFunc, ctx conte
 	}
 }
 
-func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (keyboard.Key, error) {
+func DisplayInnoDB(servers *Servers, c *container.Container, t *tcell.Terminal, interval time.Duration) (keyboard.Key, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	k := keyboard.KeyBackspace2
 	details_window, err := text.New()
@@ -92,11 +91,21 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 	}
 
 	var prev_innodb_status = make(map[string]string)
+	var last_server *Server
 
-	go refresh_innodb_info(t, cancel, ctx, 1*time.Second, func() error {
+	go refresh_innodb_info(t, cancel, ctx, interval, servers.Refresh(func(srv *Server) error {
+		if len(srv.Unavailable(ScreenInnoDB)) > 0 {
+			// the quitter goes back to the processlist
+			return nil
+		}
+		if srv != last_server {
+			// no delta between two different servers
+			prev_innodb_status = make(map[string]string)
+			last_server = srv
+		}
+		mydb := srv.DB
 		cols, data, err := GetBPFill(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var bp_info = make(map[string]string)
@@ -107,7 +116,6 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 		cols, data, err = GetRedoCapacity(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var redo_capacity = make(map[string]string)
@@ -129,7 +137,6 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 
 		cols, data, err = GetRedoInfo(mydb, innodb_redo_log_capacity)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var redo_info = make(map[string]string)
@@ -140,7 +147,6 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 		cols, data, err = GetAHI(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var ahi_info = make(map[string]string)
@@ -151,7 +157,6 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 		_, data, err = GetInnoDBStatus(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var innodb_status = make(map[string]string)
@@ -263,7 +268,7 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 
 		prev_innodb_status = innodb_status
 		return nil
-	})
+	}))
 
 	c.Update("dyn_top_container",
 		container.SplitVertical(
@@ -352,6 +357,13 @@ func DisplayInnoDB(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 			k = k2.Key
 			cancel()
 			return
+		} else if servers.HandleKey(k2) {
+			if len(servers.Current().Unavailable(ScreenInnoDB)) > 0 {
+				// the processlist tells why
+				k = keyboard.KeyBackspace2
+				cancel()
+			}
+			return
 		} else {
 			return
 		}
@@ -1,28 +1,24 @@
 package innotop
 
 import (
-	"database/sql"
 	"fmt"
 
 	"github.com/lefred/innotopgo/db"
 )
 
-func GetInnoDBStatus(mydb *sql.DB) ([]string, [][]string, error) {
-	stmt := `select variable_name, variable_value 
+const innodb_status_query = `select variable_name, variable_value 
 	         from performance_schema.global_status 
 			 where variable_name like 'innodb_%' or variable_name = 'Uptime'`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+
+func GetInnoDBStatus(mydb db.Querier) ([]string, [][]string, error) {
+	cols, data, err := db.GetData(mydb, innodb_status_query)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetAHI(mydb *sql.DB) ([]string, [][]string, error) {
+func GetAHI(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT ROUND(
             (
               SELECT Variable_value FROM sys.metrics
@@ -64,18 +60,14 @@ func GetAHI(mydb *sql.DB) ([]string, [][]string, error) {
 					WHERE variable_name = 'innodb_adaptive_hash_index_parts'
 		  ) AHIParts
 	`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetBPFill(mydb *sql.DB) ([]string, [][]string, error) {
+func GetBPFill(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT ROUND(A.num * 100.0 / B.num)  BufferPoolFull, BP_Size, BP_instances,
 					FORMAT(F.num * 100.0 / E.num,2) DiskReadRatio, 
 					ROUND(F.num*100/E.num) DiskReadRatioInt
@@ -103,18 +95,14 @@ func GetBPFill(mydb *sql.DB) ([]string, [][]string, error) {
     				WHERE variable_name = 'Innodb_buffer_pool_reads'
 				  ) F
 	`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetRedoCapacity(mydb *sql.DB) ([]string, [][]string, error) {
+func GetRedoCapacity(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT 
                                        format_bytes( (
                                                 SELECT VARIABLE_VALUE
@@ -142,18 +130,14 @@ func GetRedoCapacity(mydb *sql.DB) ([]string, [][]string, error) {
                                                 WHERE variable_name = 'innodb_log_file_size'
                                         ) AS InnoDBLogFileSizeRaw;
 					`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetRedoInfo(mydb *sql.DB, innodb_redo_log_capacity int) ([]string, [][]string, error) {
+func GetRedoInfo(mydb db.Querier, innodb_redo_log_capacity int) ([]string, [][]string, error) {
 	stmt := fmt.Sprintf(`SELECT CONCAT(
 		            (
 						SELECT FORMAT_BYTES(
@@ -190,11 +174,7 @@ func GetRedoInfo(mydb *sql.DB, innodb_redo_log_capacity int) ([]string, [][]stri
 					) AS Uptime
 	`, innodb_redo_log_capacity, innodb_redo_log_capacity, innodb_redo_log_capacity)
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1,6 +1,94 @@
 package innotop
 
+import (
+	"strings"
+	"time"
+)
+
 func version() string {
 	version := "0.3.3"
 	return version
 }
+
+func Version() string {
+	return version()
+}
+
+const (
+	ModeNormal = "normal"
+	ModeSimple = "simple"
+)
+
+const (
+	ScreenProcesslist = "processlist"
+	ScreenInnoDB      = "innodb"
+	ScreenMemory      = "memory"
+	ScreenReplication = "replication"
+	ScreenLocking     = "locking"
+	ScreenErrorlog    = "errorlog"
+	ScreenTopQueries  = "queries"
+)
+
+// screens opened from the processlist for a given thread
+const (
+	screen_explain        = "explain"
+	screen_thread_details = "thread_details"
+)
+
+var screen_titles = map[string]string{
+	ScreenProcesslist:     "Processlist",
+	ScreenInnoDB:          "InnoDB Dashboard",
+	ScreenMemory:          "Memory Dashboard",
+	ScreenReplication:     "Replication Dashboard",
+	ScreenLocking:         "Locking Info",
+	ScreenErrorlog:        "Error Log Dashboard",
+	ScreenTopQueries:      "Top Queries",
+	screen_explain:        "EXPLAIN",
+	screen_thread_details: "Thread Details",
+}
+
+// screen_modes gives the mode of the processlist loop while each screen is
+// displayed, the recorded statements are labeled with it
+var screen_modes = map[string]string{
+	ScreenProcesslist:     "processlist",
+	ScreenInnoDB:          "innodb",
+	ScreenMemory:          "memory",
+	ScreenReplication:     "replication",
+	ScreenLocking:         "locking",
+	ScreenErrorlog:        "error_log",
+	ScreenTopQueries:      "top_queries",
+	screen_explain:        "explain_normal",
+	screen_thread_details: "thread_details",
+}
+
+// screen_keys gives for each screen the key opening it from the processlist
+var screen_keys = map[string]rune{
+	ScreenInnoDB:      'I',
+	ScreenMemory:      'M',
+	ScreenReplication: 'R',
+	ScreenLocking:     'L',
+	ScreenErrorlog:    'E',
+	ScreenTopQueries:  'T',
+}
+
+// Options are the settings given on the command line
+type Options struct {
+	Mode     string
+	Screen   string
+	Interval time.Duration
+	// Filters are the named processlist filters of the configuration file
+	Filters map[string]string
+}
+
+func ValidScreen(screen string) bool {
+	if screen == ScreenProcesslist {
+		return true
+	}
+	_, ok := screen_keys[screen]
+	return ok
+}
+
+func ScreenNames() string {
+	return strings.Join([]string{ScreenProcesslist, ScreenInnoDB, ScreenMemory,
+		ScreenReplication, ScreenLocking, ScreenErrorlog, ScreenTopQueries}, ", ")
+}
@@ -0,0 +1,17 @@
+package innotop
+
+import (
+	"github.com/lefred/innotopgo/db"
+)
+
+// newCapabilities returns the capabilities probed on a server of the version
+func newCapabilities(version string, performance_schema bool, sys_schema bool) db.Capabilities {
+	flag := func(on bool) string {
+		if on {
+			return "1"
+		}
+		return "0"
+	}
+	return db.NewCapabilities([]string{"version_comment", "version", "performance_schema", "sys_schema", "error_log"},
+		[]string{"Source distribution", version, flag(performance_schema), flag(sys_schema), flag(performance_schema)})
+}
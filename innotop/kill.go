@@ -1,30 +1,106 @@
 package innotop
 
 import (
-	"database/sql"
-	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
 )
 
-func KillQuery(mydb *sql.DB, thread_id string) error {
-	// TODO it works only with conn_id
-	stmt := `select pps.PROCESSLIST_ID AS conn_id from performance_schema.threads pps where thread_id = ` + thread_id + ` LIMIT 1`
-	rows, err := db.Query(mydb, stmt)
-
+// Kill kills the statement running on the connection, or the connection
+// itself when connection is set
+func Kill(mydb db.Querier, conn_id string, connection bool) error {
+	// KILL does not take placeholders, the id must be a number
+	id, err := strconv.ParseUint(conn_id, 10, 64)
 	if err != nil {
-		return (err)
+		return fmt.Errorf("invalid connection id '%s'", conn_id)
+	}
+	if connection {
+		return db.RunQuery(mydb, fmt.Sprintf("kill connection %d", id))
+	}
+	return db.RunQuery(mydb, fmt.Sprintf("kill query %d", id))
+}
+
+// KillDialog asks the confirmation of the kill of one or several threads of
+// the processlist, then shows which kills succeeded.
+type KillDialog struct {
+	Rows []db.Row
+	// Connection kills the connections instead of their statements
+	Connection bool
+	// Skipped is the number of threads left out, like the connections of
+	// innotopgo
+	Skipped int
+	// Errors are the results of the kills, nil until they are run
+	Errors []error
+}
+
+// Done returns whether the kills were run
+func (dialog *KillDialog) Done() bool {
+	return dialog.Errors != nil
+}
+
+func (dialog *KillDialog) kind() string {
+	if dialog.Connection {
+		return "KILL CONNECTION"
+	}
+	return "KILL QUERY"
+}
+
+// Run kills the threads, a failed kill does not stop the other ones
+func (dialog *KillDialog) Run(mydb db.Querier) {
+	dialog.Errors = make([]error, len(dialog.Rows))
+	for i, row := range dialog.Rows {
+		dialog.Errors[i] = Kill(mydb, row.Get("conn_id").String(), dialog.Connection)
+	}
+}
+
+// Title returns the border title with the keys of the dialog
+func (dialog *KillDialog) Title() string {
+	if dialog.Done() {
+		return fmt.Sprintf("%s (<-- <Backspace> to return to Processlist)", dialog.kind())
+	}
+	return fmt.Sprintf("%s (<q> QUERY - <c> CONNECTION - <y> to confirm - <Backspace> to cancel)", dialog.kind())
+}
+
+// Display writes the threads to kill, or once killed the result of each kill
+func (dialog *KillDialog) Display(window *text.Text) {
+	window.Reset()
+	if !dialog.Done() {
+		line := fmt.Sprintf("\n %s on %d thread(s), press <y> to confirm:\n\n", dialog.kind(), len(dialog.Rows))
+		window.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+	} else {
+		failed := 0
+		for _, err := range dialog.Errors {
+			if err != nil {
+				failed++
+			}
+		}
+		line := fmt.Sprintf("\n %s: %d succeeded, %d failed\n\n", dialog.kind(), len(dialog.Rows)-failed, failed)
+		window.Write(line, text.WriteCellOpts(cell.Bold()))
 	}
-	_, data, _ := db.GetData(rows)
-	var conn_id string
-	if len(data) < 1 {
-		err = errors.New("not found")
-		return err
+	header := fmt.Sprintf(" %-7v %-7v %-25v %-12v %10v %-65v\n", "Conn", "Cmd", "User", "Db", "Time", "Query")
+	window.Write(header, text.WriteCellOpts(cell.Bold()))
+	for i, row := range dialog.Rows {
+		line := fmt.Sprintf(" %-7v %-7v %-25v %-12v %10v %-65v\n",
+			ChunkString(row.Get("conn_id").String(), 7),
+			ChunkString(row.Get("command").String(), 7),
+			ChunkString(row.Get("user").String(), 25),
+			ChunkString(row.Get("db").String(), 12),
+			ChunkString(row.Get("statement_latency").String(), 10),
+			ChunkString(row.Get("current_statement").String(), 65))
+		if !dialog.Done() {
+			window.Write(line)
+		} else if err := dialog.Errors[i]; err != nil {
+			window.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorRed)))
+			window.Write(fmt.Sprintf("         failed: %v\n", err), text.WriteCellOpts(cell.FgColor(cell.ColorRed)))
+		} else {
+			window.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorGreen)))
+		}
 	}
-	for _, row := range data {
-		conn_id = row[0]
+	if dialog.Skipped > 0 {
+		window.Write(fmt.Sprintf("\n %d connection(s) of innotopgo left out\n", dialog.Skipped),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
 	}
-	stmt = `kill query ` + conn_id
-	err = db.RunQuery(mydb, stmt)
-	return err
 }
@@ -0,0 +1,136 @@
+package innotop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// KillOptions are the settings of the kill daemon
+type KillOptions struct {
+	Interval time.Duration
+	// DryRun only logs the threads the rules would kill
+	DryRun bool
+	// Log receives a line for every thread matched
+	Log io.Writer
+}
+
+// killServer is the state of the daemon for one server
+type killServer struct {
+	*Server
+	// own are the connections of innotopgo, never killed
+	own ProcesslistHide
+	// seen are the statements already logged, by connection, so that a
+	// statement running for a while is logged once
+	seen map[string]killSeen
+}
+
+type killSeen struct {
+	statement string
+	time      float64
+}
+
+// KillDaemon evaluates the rules against the processlist of each server at
+// every interval, until it is interrupted. Only the foreground threads are
+// matched, the replication and background threads are never killed.
+func KillDaemon(servers *Servers, rules []KillRule, opts KillOptions) error {
+	var list []*killServer
+	for _, srv := range servers.List() {
+		if err := srv.LoadInfo(); err != nil {
+			return err
+		}
+		if reason := srv.Unavailable(ScreenProcesslist); len(reason) > 0 {
+			return fmt.Errorf("%s: the processlist is not available on this server because %s", srv.Name, reason)
+		}
+		list = append(list, &killServer{Server: srv, seen: map[string]killSeen{}})
+	}
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name+" ("+rule.Action+")")
+	}
+	mode := "killing"
+	if opts.DryRun {
+		mode = "dry run, nothing is killed"
+	}
+	killLog(opts.Log, "started on %d server(s), %s, rules: %s", len(list), mode, strings.Join(names, ", "))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		for _, srv := range list {
+			srv.check(rules, opts)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			killLog(opts.Log, "stopped")
+			return nil
+		}
+	}
+}
+
+// check reads the processlist of the server and acts on the threads
+// matching the rules
+func (srv *killServer) check(rules []KillRule, opts KillOptions) {
+	if !srv.reconnect.Check(srv.DB) {
+		return
+	}
+	result, err := GetProcesslist(srv.DB, srv.Capabilities)
+	if err != nil {
+		if srv.reconnect.Lost(err) {
+			killLog(opts.Log, "server=%s connection lost: %v", srv.Name, err)
+		} else {
+			killLog(opts.Log, "server=%s processlist failed: %v", srv.Name, err)
+		}
+		return
+	}
+	srv.own.See(result)
+	seen := map[string]killSeen{}
+	for _, row := range result.Rows {
+		if row.Get("thread_type").String() != "foreground" || srv.own.Own(row) {
+			continue
+		}
+		matched, action := matchRules(rules, row)
+		if len(matched) == 0 {
+			continue
+		}
+		conn_id := row.Get("conn_id").String()
+		latency, _ := row.Get("sort_time").Float64()
+		current := killSeen{statement: fullStatement(row).String(), time: latency}
+		seen[conn_id] = current
+		prev, ok := srv.seen[conn_id]
+		var outcome string
+		switch {
+		case action == KillActionLog || opts.DryRun:
+			// a statement still running was logged at a previous interval
+			if ok && prev.statement == current.statement && prev.time <= current.time {
+				continue
+			}
+			outcome = "logged"
+			if action != KillActionLog {
+				outcome = "dry-run"
+			}
+		default:
+			outcome = "killed"
+			if err := Kill(srv.DB, conn_id, action == KillActionConnection); err != nil {
+				outcome = "failed: " + err.Error()
+			}
+		}
+		killLog(opts.Log, "server=%s rule=%s action=%s result=%q conn_id=%s user=%q db=%q command=%q state=%q time=%.1fs statement=%q",
+			srv.Name, strings.Join(matched, ","), action, outcome, conn_id, row.Get("user").String(), row.Get("db").String(),
+			row.Get("command").String(), row.Get("state").String(), latency/1e12, current.statement)
+	}
+	srv.seen = seen
+}
+
+// killLog writes a line of the log of the daemon, prefixed with the time
+func killLog(out io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(out, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,127 @@
+package innotop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// the actions of the kill rules, from the mildest
+const (
+	KillActionLog        = "log"
+	KillActionQuery      = "kill-query"
+	KillActionConnection = "kill-connection"
+)
+
+var kill_actions = []string{KillActionLog, KillActionQuery, KillActionConnection}
+
+// kill_rule_terms gives for each option of a rule the filter term it
+// becomes, the statement is a regular expression and min-time a duration
+var kill_rule_terms = map[string]filterTerm{
+	"user":      {field: "user", operator: "="},
+	"host":      {field: "host", operator: "="},
+	"db":        {field: "db", operator: "="},
+	"command":   {field: "cmd", operator: "="},
+	"state":     {field: "state", operator: "="},
+	"statement": {field: "stmt", operator: "~"},
+	"min-time":  {field: "time", operator: ">="},
+}
+
+// KillRule matches the threads to log or to kill, like pt-kill. All the
+// options of the rule have to match.
+type KillRule struct {
+	Name   string
+	Action string
+	terms  []filterTerm
+}
+
+// NewKillRules builds the rules of a rules file, sorted by name. Besides
+// the options of kill_rule_terms, match takes any processlist filter like
+// rows>1000000.
+func NewKillRules(groups map[string]map[string]string) ([]KillRule, error) {
+	var rules []KillRule
+	for name, options := range groups {
+		rule := KillRule{Name: name, Action: options["action"]}
+		if len(rule.Action) == 0 {
+			return nil, fmt.Errorf("rule '%s': no action, use one of: %s", name, strings.Join(kill_actions, ", "))
+		}
+		if rule.severity() < 0 {
+			return nil, fmt.Errorf("rule '%s': invalid action '%s', use one of: %s", name, rule.Action, strings.Join(kill_actions, ", "))
+		}
+		for option, value := range options {
+			switch option {
+			case "action":
+				continue
+			case "match":
+				terms, err := parseFilter(value)
+				if err != nil {
+					return nil, fmt.Errorf("rule '%s': %v", name, err)
+				}
+				rule.terms = append(rule.terms, terms...)
+				continue
+			}
+			term, ok := kill_rule_terms[option]
+			if !ok {
+				return nil, fmt.Errorf("rule '%s': unknown option '%s'", name, option)
+			}
+			term.value = value
+			if err := term.compile(); err != nil {
+				return nil, fmt.Errorf("rule '%s': %v", name, err)
+			}
+			rule.terms = append(rule.terms, term)
+		}
+		if len(rule.terms) == 0 {
+			// a rule without condition would kill every thread
+			return nil, fmt.Errorf("rule '%s': no condition", name)
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rule defined, use groups like [rule <name>]")
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// Match returns whether the row of the processlist matches all the options
+// of the rule
+func (rule *KillRule) Match(row db.Row) bool {
+	for i := range rule.terms {
+		if !rule.terms[i].match(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// severity returns the position of the action in kill_actions, -1 when it
+// is unknown
+func (rule *KillRule) severity() int {
+	for i, action := range kill_actions {
+		if rule.Action == action {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchRules returns the names of the rules matching the row and the most
+// severe of their actions, empty when no rule matches
+func matchRules(rules []KillRule, row db.Row) ([]string, string) {
+	var names []string
+	action := ""
+	severity := -1
+	for i := range rules {
+		if !rules[i].Match(row) {
+			continue
+		}
+		names = append(names, rules[i].Name)
+		if s := rules[i].severity(); s > severity {
+			severity = s
+			action = rules[i].Action
+		}
+	}
+	return names, action
+}
@@ -0,0 +1,188 @@
+package innotop
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+var kill_rule_groups = map[string]map[string]string{
+	"long-report": {"action": KillActionQuery, "user": "report", "min-time": "60s"},
+	"sleepers":    {"action": KillActionConnection, "command": "Sleep", "host": "10.0.0.9"},
+	"orders":      {"action": KillActionLog, "statement": "from orders"},
+	"slow":        {"action": KillActionLog, "match": "time>1s db=shop"},
+}
+
+// kill_rule_rows are the threads of the processlist matched by the rules
+var kill_rule_rows = [][]interface{}{
+	{"Query", 48, 9, nil, "executing", "app@10.0.0.5", "shop", "select * from orders",
+		"2.51 s", "1.00 us", 2510000000000, 1000000, 1200, 4096, nil, "foreground", 0},
+	{"Query", 51, 12, nil, "Sending data", "report@10.0.0.9", "reporting", "select sum(amount) from sales",
+		"2 min", "1.00 us", 120000000000000, 1000000, 90000, 4096, nil, "foreground", 0},
+	{"Sleep", 52, 13, nil, nil, "report@10.0.0.9", nil, nil,
+		nil, nil, 0, nil, nil, nil, nil, "foreground", 0},
+	{"Sleep", 53, 14, nil, nil, "report@10.0.0.7", nil, nil,
+		nil, nil, 0, nil, nil, nil, nil, "foreground", 0},
+	// the connection of innotopgo and a background thread are never killed
+	{"Sleep", 60, 20, nil, nil, "report@10.0.0.9", nil, nil,
+		nil, nil, 0, nil, nil, nil, nil, "foreground", 1},
+	{"Sleep", 61, 21, nil, nil, "report@10.0.0.9", nil, nil,
+		nil, nil, 0, nil, nil, nil, nil, "background", 0},
+}
+
+func TestMatchRules(t *testing.T) {
+	rules, err := NewKillRules(kill_rule_groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"long-report", "orders", "sleepers", "slow"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rules are %v, want %v", names, want)
+	}
+
+	result := db.NewResult(processlist_columns, kill_rule_rows)
+	tests := []struct {
+		conn_id string
+		matched []string
+		action  string
+	}{
+		// statement and match with time and db
+		{conn_id: "9", matched: []string{"orders", "slow"}, action: KillActionLog},
+		// user and min-time, the strongest action wins
+		{conn_id: "12", matched: []string{"long-report"}, action: KillActionQuery},
+		// command and host
+		{conn_id: "13", matched: []string{"sleepers"}, action: KillActionConnection},
+		// another host
+		{conn_id: "14"},
+	}
+	for i, test := range tests {
+		row := result.Rows[i]
+		if conn_id := row.Get("conn_id").String(); conn_id != test.conn_id {
+			t.Fatalf("row %d is connection %s, want %s", i, conn_id, test.conn_id)
+		}
+		matched, action := matchRules(rules, row)
+		if !reflect.DeepEqual(matched, test.matched) || action != test.action {
+			t.Errorf("connection %s matches %v with %q, want %v with %q", test.conn_id, matched, action, test.matched, test.action)
+		}
+	}
+}
+
+func TestMatchRulesFullStatement(t *testing.T) {
+	rules, err := NewKillRules(map[string]map[string]string{
+		"customers": {"action": KillActionLog, "statement": `\bjoin\s+customers\b`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := "select o.id, o.total, c.name from orders o join customers c on c.id = o.customer_id where o.status = 'new'"
+	// sys.format_statement keeps the start and the end of the statements
+	// longer than 64 characters
+	shortened := stmt[:30] + " ... " + stmt[len(stmt)-29:]
+	if len(stmt) <= 64 || strings.Contains(shortened, "customers") {
+		t.Fatalf("the statement %q is not shortened by the sys schema", stmt)
+	}
+	result := db.NewResult(processlist_columns, [][]interface{}{
+		{"Query", 48, 9, nil, "executing", "app@10.0.0.5", "shop", shortened,
+			"2.51 s", "1.00 us", 2510000000000, 1000000, 1200, 4096, nil, "foreground", 0, stmt},
+		// the processlists read without the full statement
+		{"Query", 49, 10, nil, "executing", "app@10.0.0.5", "shop", stmt,
+			"2.51 s", "1.00 us", 2510000000000, 1000000, 1200, 4096, nil, "foreground", 0, nil},
+		{"Query", 50, 11, nil, "executing", "app@10.0.0.5", "shop", shortened,
+			"2.51 s", "1.00 us", 2510000000000, 1000000, 1200, 4096, nil, "foreground", 0, nil},
+	})
+	for i, want := range []bool{true, true, false} {
+		if matched, _ := matchRules(rules, result.Rows[i]); (len(matched) > 0) != want {
+			t.Errorf("connection %s matches %v, want a match %v", result.Rows[i].Get("conn_id").String(), matched, want)
+		}
+	}
+}
+
+func TestNewKillRulesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups map[string]map[string]string
+		err    string
+	}{
+		{name: "no rule", groups: map[string]map[string]string{}, err: "no rule defined"},
+		{name: "no action", groups: map[string]map[string]string{"r": {"user": "app"}}, err: "no action"},
+		{name: "invalid action", groups: map[string]map[string]string{"r": {"action": "drop", "user": "app"}}, err: "invalid action 'drop'"},
+		{name: "no condition", groups: map[string]map[string]string{"r": {"action": KillActionLog}}, err: "no condition"},
+		{name: "unknown option", groups: map[string]map[string]string{"r": {"action": KillActionLog, "schema": "shop"}}, err: "unknown option 'schema'"},
+		{name: "invalid min-time", groups: map[string]map[string]string{"r": {"action": KillActionLog, "min-time": "soon"}}, err: "invalid duration 'soon'"},
+		{name: "invalid statement", groups: map[string]map[string]string{"r": {"action": KillActionLog, "statement": "("}}, err: "invalid regular expression"},
+		{name: "invalid match", groups: map[string]map[string]string{"r": {"action": KillActionLog, "match": "rows~1"}}, err: "cannot be matched"},
+	}
+	for _, test := range tests {
+		_, err := NewKillRules(test.groups)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error is %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestKillServerCheck(t *testing.T) {
+	rules, err := NewKillRules(kill_rule_groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := newCapabilities("8.0.36", true, true)
+	tests := []struct {
+		name    string
+		dry_run bool
+		// killed are the statements run on the server
+		killed []string
+		// results are the results logged, by connection
+		results map[string]string
+	}{
+		{name: "execute", killed: []string{"kill query 12", "kill connection 13"},
+			results: map[string]string{"9": "logged", "12": "killed", "13": "killed"}},
+		{name: "dry run", dry_run: true,
+			results: map[string]string{"9": "logged", "12": "dry-run", "13": "dry-run"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := db.NewFake()
+			fake.Set(processlistStatement(caps), processlist_columns, kill_rule_rows...)
+			srv := &killServer{Server: &Server{Name: "db1", DB: fake, Capabilities: caps}, seen: map[string]killSeen{}}
+			var log bytes.Buffer
+			opts := KillOptions{DryRun: test.dry_run, Log: &log}
+
+			srv.check(rules, opts)
+			if killed := fake.Executed(); len(killed) != len(test.killed) || (len(killed) > 0 && !reflect.DeepEqual(killed, test.killed)) {
+				t.Errorf("statements run are %q, want %q", killed, test.killed)
+			}
+			results := map[string]string{}
+			for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+				var conn_id, outcome string
+				for _, field := range strings.Fields(line) {
+					if strings.HasPrefix(field, "conn_id=") {
+						conn_id = strings.TrimPrefix(field, "conn_id=")
+					}
+					if strings.HasPrefix(field, "result=") {
+						outcome = strings.Trim(strings.TrimPrefix(field, "result="), `"`)
+					}
+				}
+				results[conn_id] = outcome
+			}
+			if !reflect.DeepEqual(results, test.results) {
+				t.Errorf("results are %v, want %v\n%s", results, test.results, log.String())
+			}
+
+			// the statements still running are logged once
+			log.Reset()
+			srv.check(rules, opts)
+			if test.dry_run && log.Len() > 0 {
+				t.Errorf("the second check logged again:\n%s", log.String())
+			}
+			if !test.dry_run && strings.Contains(log.String(), "conn_id=9 ") {
+				t.Errorf("the second check logged connection 9 again:\n%s", log.String())
+			}
+		})
+	}
+}
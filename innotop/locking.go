@@ -2,7 +2,6 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"strconv"
@@ -15,8 +14,9 @@ import (
 	"github.com/mum4k/termdash/widgets/text"
 )
 
-func getMetadaLocks(mydb *sql.DB, thread_id string) ([][]string, error) {
-	stmt := `WITH mdl_lock_summary AS (
+// metadata_locks_query sums up the metadata locks held and waited for by a
+// connection
+const metadata_locks_query = `WITH mdl_lock_summary AS (
             SELECT
             owner_thread_id,
             GROUP_CONCAT(
@@ -35,13 +35,10 @@ func getMetadaLocks(mydb *sql.DB, thread_id string) ([][]string, error) {
             mdl_lock_summary.lock_summary
             FROM sys.processlist ps
             INNER JOIN mdl_lock_summary ON ps.thd_id=mdl_lock_summary.owner_thread_id
-            WHERE conn_id`
-	stmt = fmt.Sprintf("%s=%s;", stmt, thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, err
-	}
-	_, data, err := db.GetData(rows)
+            WHERE conn_id=?`
+
+func getMetadaLocks(mydb db.Querier, thread_id string) ([][]string, error) {
+	_, data, err := db.GetData(mydb, metadata_locks_query, thread_id)
 	if err != nil {
 		return nil, err
 	}
@@ -53,19 +50,16 @@ func getMetadaLocks(mydb *sql.DB, thread_id string) ([][]string, error) {
 	return data, err
 }
 
-func getDataLocks(mydb *sql.DB, thread_id string) ([][]string, error) {
-	stmt := `SELECT OBJECT_SCHEMA, OBJECT_NAME, LOCK_TYPE,
+const data_locks_query = `SELECT OBJECT_SCHEMA, OBJECT_NAME, LOCK_TYPE,
                          LOCK_MODE, LOCK_STATUS, INDEX_NAME, GROUP_CONCAT(LOCK_DATA SEPARATOR '|')
                          FROM INFORMATION_SCHEMA.INNODB_TRX
                          JOIN performance_schema.data_locks d
                            ON d.ENGINE_TRANSACTION_ID = trx_id
-                         WHERE trx_mysql_thread_id`
-	stmt = fmt.Sprintf("%s=%s GROUP BY 1,2,3,4,5,6 ORDER BY 1,2, 3 DESC, 6;", stmt, thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, err
-	}
-	_, data, err := db.GetData(rows)
+                         WHERE trx_mysql_thread_id=?
+                         GROUP BY 1,2,3,4,5,6 ORDER BY 1,2, 3 DESC, 6`
+
+func getDataLocks(mydb db.Querier, thread_id string) ([][]string, error) {
+	_, data, err := db.GetData(mydb, data_locks_query, thread_id)
 	if err != nil {
 		return nil, err
 	}
@@ -77,19 +71,16 @@ func getDataLocks(mydb *sql.DB, thread_id string) ([][]string, error) {
 	return data, err
 }
 
-func getLockInfo(mydb *sql.DB, thread_id string) ([][]string, error) {
-	stmt := `SELECT FORMAT_PICO_TIME(trx.timer_wait) AS trx_duration,
+const lock_info_query = `SELECT FORMAT_PICO_TIME(trx.timer_wait) AS trx_duration,
 					COUNT(case when lock_status='GRANTED' then 1 else null end) AS row_locks_held,
 					COUNT(case when lock_status='PENDING' then 1 else null end) AS row_locks_pending
 			 FROM performance_schema.events_transactions_current trx
 			 LEFT JOIN performance_schema.data_locks USING (thread_id)
-			 WHERE thread_id`
-	stmt = fmt.Sprintf("%s=%s GROUP BY thread_id, timer_wait ORDER BY TIMER_WAIT DESC;", stmt, thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, err
-	}
-	_, data, err := db.GetData(rows)
+			 WHERE thread_id=?
+			 GROUP BY thread_id, timer_wait ORDER BY TIMER_WAIT DESC`
+
+func getLockInfo(mydb db.Querier, thread_id string) ([][]string, error) {
+	_, data, err := db.GetData(mydb, lock_info_query, thread_id)
 	if err != nil {
 		return nil, err
 	}
@@ -101,13 +92,10 @@ func getLockInfo(mydb *sql.DB, thread_id string) ([][]string, error) {
 	return data, err
 }
 
-func GetQueryConnByThreadId(mydb *sql.DB, thread_id string) (string, string, error) {
-	stmt := fmt.Sprintf("select conn_id, current_statement from sys.x$processlist where thd_id=%s;", thread_id)
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return "", "", err
-	}
-	_, data, err := db.GetData(rows)
+const query_conn_query = "select conn_id, current_statement from sys.x$processlist where thd_id=?"
+
+func GetQueryConnByThreadId(mydb db.Querier, thread_id string) (string, string, error) {
+	_, data, err := db.GetData(mydb, query_conn_query, thread_id)
 	if err != nil {
 		return "", "", err
 	}
@@ -125,7 +113,7 @@ func GetQueryConnByThreadId(mydb *sql.DB, thread_id string) (string, string, err
 	return conn_id, query_text, err
 }
 
-func DisplayLocking(ctx context.Context, mydb *sql.DB, c *container.Container, top_window *text.Text, main_window *text.Text, thread_id string) error {
+func DisplayLocking(ctx context.Context, mydb db.Querier, c *container.Container, top_window *text.Text, main_window *text.Text, thread_id string) error {
 	conn_id, query_text, err := GetQueryConnByThreadId(mydb, thread_id)
 	if err != nil {
 		return err
@@ -185,19 +173,12 @@ func DisplayLocking(ctx context.Context, mydb *sql.DB, c *container.Container, t
                                     FROM INFORMATION_SCHEMA.INNODB_TABLES it
                                     LEFT JOIN INFORMATION_SCHEMA.INNODB_INDEXES ii
                                            ON ii.table_id = it.table_id AND
-                                              (ii.name = '`
-			stmt = stmt + row[5]
-			stmt = stmt + `' OR ii.name='PRIMARY')
+                                              (ii.name = ? OR ii.name='PRIMARY')
                                    LEFT JOIN INFORMATION_SCHEMA.INNODB_FIELDS ifi
                                            ON ifi.index_id = ii.index_id
-                                   WHERE it.name = '`
-			stmt = stmt + row[0] + "/" + row[1]
-			stmt = stmt + `' ORDER BY ii.NAME, POS`
-			rows, err := db.Query(mydb, stmt)
-			if err != nil {
-				return err
-			}
-			_, data2, err := db.GetData(rows)
+                                   WHERE it.name = ?
+                                   ORDER BY ii.NAME, POS`
+			_, data2, err := db.GetData(mydb, stmt, row[5], row[0]+"/"+row[1])
 			if err != nil {
 				return err
 			}
@@ -298,12 +279,8 @@ func DisplayLocking(ctx context.Context, mydb *sql.DB, c *container.Container, t
 		"   FROM performance_schema.threads AS t" +
 		"   JOIN sys.innodb_lock_waits AS ilw ON ilw.waiting_pid = t.PROCESSLIST_ID " +
 		"   JOIN sys.processlist proc ON proc.conn_id = blocking_pid" +
-		"   WHERE waiting_pid=" + conn_id
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return err
-	}
-	_, data, err = db.GetData(rows)
+		"   WHERE waiting_pid=?"
+	_, data, err = db.GetData(mydb, stmt, conn_id)
 	if err != nil {
 		return err
 	}
@@ -318,12 +295,8 @@ func DisplayLocking(ctx context.Context, mydb *sql.DB, c *container.Container, t
 		"             waiting_trx_rows_locked, waiting_trx_started, wait_age_secs, processlist_id" +
 		"       FROM performance_schema.threads AS t" +
 		"       JOIN sys.innodb_lock_waits AS ilw" +
-		"         ON ilw.waiting_pid = t.PROCESSLIST_ID where blocking_pid=" + conn_id
-	rows, err = db.Query(mydb, stmt)
-	if err != nil {
-		return err
-	}
-	_, data, err = db.GetData(rows)
+		"         ON ilw.waiting_pid = t.PROCESSLIST_ID where blocking_pid=?"
+	_, data, err = db.GetData(mydb, stmt, conn_id)
 	if err != nil {
 		return err
 	}
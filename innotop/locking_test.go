@@ -0,0 +1,80 @@
+package innotop
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestLockingGetters(t *testing.T) {
+	fake := db.NewFake(
+		db.Fixture{Statement: metadata_locks_query, Args: []interface{}{"9"}, Columns: []string{"lock_summary"},
+			Rows: [][]interface{}{{"GRANTED SHARED_READ on shop.orders"}}},
+		db.Fixture{Statement: metadata_locks_query, Columns: []string{"lock_summary"}},
+		db.Fixture{Statement: data_locks_query, Args: []interface{}{"9"},
+			Columns: []string{"OBJECT_SCHEMA", "OBJECT_NAME", "LOCK_TYPE", "LOCK_MODE", "LOCK_STATUS", "INDEX_NAME", "LOCK_DATA"},
+			Rows: [][]interface{}{
+				{"shop", "orders", "TABLE", "IX", "GRANTED", nil, nil},
+				{"shop", "orders", "RECORD", "X", "GRANTED", "PRIMARY", "1|2"},
+			}},
+		db.Fixture{Statement: data_locks_query, Columns: []string{"OBJECT_SCHEMA"}},
+		db.Fixture{Statement: lock_info_query, Args: []interface{}{"48"},
+			Columns: []string{"trx_duration", "row_locks_held", "row_locks_pending"},
+			Rows:    [][]interface{}{{"2.51 s", 2, 0}}},
+		db.Fixture{Statement: lock_info_query, Columns: []string{"trx_duration"}},
+		db.Fixture{Statement: query_conn_query, Args: []interface{}{"48"}, Columns: []string{"conn_id", "current_statement"},
+			Rows: [][]interface{}{{9, "update orders set paid = 1"}}},
+		db.Fixture{Statement: query_conn_query, Columns: []string{"conn_id", "current_statement"}},
+	)
+
+	tests := []struct {
+		name      string
+		get       func(thread_id string) ([][]string, error)
+		thread_id string
+		want      [][]string
+		// err is true when nothing found is an error
+		err bool
+	}{
+		{name: "metadata locks", get: func(id string) ([][]string, error) { return getMetadaLocks(fake, id) },
+			thread_id: "9", want: [][]string{{"GRANTED SHARED_READ on shop.orders"}}},
+		{name: "no metadata lock", get: func(id string) ([][]string, error) { return getMetadaLocks(fake, id) },
+			thread_id: "10", err: true},
+		{name: "data locks", get: func(id string) ([][]string, error) { return getDataLocks(fake, id) },
+			thread_id: "9", want: [][]string{{"shop", "orders", "TABLE", "IX", "GRANTED", "", ""}, {"shop", "orders", "RECORD", "X", "GRANTED", "PRIMARY", "1|2"}}},
+		{name: "no data lock", get: func(id string) ([][]string, error) { return getDataLocks(fake, id) },
+			thread_id: "10", want: [][]string{}},
+		{name: "lock info", get: func(id string) ([][]string, error) { return getLockInfo(fake, id) },
+			thread_id: "48", want: [][]string{{"2.51 s", "2", "0"}}},
+		{name: "no transaction", get: func(id string) ([][]string, error) { return getLockInfo(fake, id) },
+			thread_id: "49", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.get(test.thread_id)
+			if test.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != len(test.want) || (len(data) > 0 && !reflect.DeepEqual(data, test.want)) {
+				t.Errorf("got %q, want %q", data, test.want)
+			}
+		})
+	}
+
+	conn_id, query_text, err := GetQueryConnByThreadId(fake, "48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn_id != "9" || query_text != "update orders set paid = 1" {
+		t.Errorf("GetQueryConnByThreadId(48) = %s, %s", conn_id, query_text)
+	}
+	if _, _, err := GetQueryConnByThreadId(fake, "49"); err == nil {
+		t.Errorf("GetQueryConnByThreadId found a thread without fixture")
+	}
+}
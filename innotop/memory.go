@@ -2,7 +2,6 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
@@ -34,7 +33,13 @@ func refresh_memory_info(t *tcell.Terminal, cancel context.CancelFunc, ctx conte
 	}
 }
 
-func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (keyboard.Key, error) {
+func newMemoryGraph() (*sparkline.SparkLine, error) {
+	return sparkline.New(
+		sparkline.Color(cell.ColorBlue),
+	)
+}
+
+func DisplayMemory(servers *Servers, c *container.Container, t *tcell.Terminal, interval time.Duration) (keyboard.Key, error) {
 	ctxmem, cancel := context.WithCancel(context.Background())
 	k := keyboard.KeyBackspace2
 	tot_mem_window, err := text.New()
@@ -48,9 +53,7 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		return k, err
 	}
 
-	mem_graph, err := sparkline.New(
-		sparkline.Color(cell.ColorBlue),
-	)
+	mem_graph, err := newMemoryGraph()
 	if err != nil {
 		cancel()
 		return k, err
@@ -63,11 +66,29 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 	}
 
 	var prev_mem_info = make(map[string]string)
+	var last_server *Server
 
-	go refresh_memory_info(t, cancel, ctxmem, 1*time.Second, func() error {
+	go refresh_memory_info(t, cancel, ctxmem, interval, servers.Refresh(func(srv *Server) error {
+		if len(srv.Unavailable(ScreenMemory)) > 0 {
+			// the quitter goes back to the processlist
+			return nil
+		}
+		if srv != last_server {
+			// no delta nor graph history between two different servers
+			prev_mem_info = make(map[string]string)
+			if last_server != nil {
+				new_graph, err := newMemoryGraph()
+				if err != nil {
+					return err
+				}
+				mem_graph = new_graph
+				c.Update("memory_alloc_container", container.PlaceWidget(mem_graph))
+			}
+			last_server = srv
+		}
+		mydb := srv.DB
 		cols, data, err := GetTempMem(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var mem_info = make(map[string]string)
@@ -78,7 +99,6 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 		_, data, err = GetTempAlloc(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var mem_alloc = make(map[string][]string)
@@ -87,7 +107,6 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 		_, data, err = GetUserMemAlloc(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 		var user_mem_alloc = make(map[string][]string)
@@ -97,7 +116,6 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 
 		_, code_mem_alloc, err := GetCodeMemAlloc(mydb)
 		if err != nil {
-			cancel()
 			return err
 		}
 
@@ -193,7 +211,7 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 		}
 
 		return nil
-	})
+	}))
 
 	c.Update("dyn_top_container",
 		container.SplitVertical(
@@ -250,6 +268,13 @@ func DisplayMemory(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (key
 			k = k2.Key
 			cancel()
 			return
+		} else if servers.HandleKey(k2) {
+			if len(servers.Current().Unavailable(ScreenMemory)) > 0 {
+				// the processlist tells why
+				k = keyboard.KeyBackspace2
+				cancel()
+			}
+			return
 		} else {
 			return
 		}
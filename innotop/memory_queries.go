@@ -1,12 +1,10 @@
 package innotop
 
 import (
-	"database/sql"
-
 	"github.com/lefred/innotopgo/db"
 )
 
-func GetTempMem(mydb *sql.DB) ([]string, [][]string, error) {
+func GetTempMem(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT FORMAT(B.num * 100.0 / A.num,2) AS TempTablesDiskRatio,
        ROUND(B.num * 100/A.num) AS TempTablesDiskRatioInt,
        B.num As TempTablesDisk,  A.num As TempTables,
@@ -39,52 +37,40 @@ func GetTempMem(mydb *sql.DB) ([]string, [][]string, error) {
          WHERE variable_name = 'max_heap_table_size') K
 	`
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetTempAlloc(mydb *sql.DB) ([]string, [][]string, error) {
+func GetTempAlloc(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT event_name,
 	                format_bytes(CURRENT_NUMBER_OF_BYTES_USED) AS current_alloc,
 	                format_bytes(HIGH_NUMBER_OF_BYTES_USED) AS high_alloc
 			  FROM performance_schema.memory_summary_global_by_event_name
 			 WHERE event_name LIKE 'memory/temptable/%'`
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetUserMemAlloc(mydb *sql.DB) ([]string, [][]string, error) {
+func GetUserMemAlloc(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT user, current_allocated, current_max_alloc
 	        FROM sys.memory_by_user_by_current_bytes
 			WHERE user != "background"`
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 	return cols, data, err
 }
 
-func GetCodeMemAlloc(mydb *sql.DB) ([]string, [][]string, error) {
+func GetCodeMemAlloc(mydb db.Querier) ([]string, [][]string, error) {
 	stmt := `SELECT SUBSTRING_INDEX(event_name,'/',2) AS code_area,
        				format_bytes(SUM(current_alloc)) AS current_alloc,
 					sum(current_alloc) current_alloc_num
@@ -92,11 +78,7 @@ func GetCodeMemAlloc(mydb *sql.DB) ([]string, [][]string, error) {
        		 GROUP BY SUBSTRING_INDEX(event_name,'/',2)
        		 ORDER BY SUM(current_alloc) DESC`
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -2,12 +2,12 @@ package innotop
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lefred/innotopgo/db"
@@ -27,16 +27,27 @@ import (
 
 const redrawInterval = 1000 * time.Millisecond
 
-func Processlist(mydb *sql.DB, displaytype string) error {
+func Processlist(servers *Servers, opts Options) error {
 
-	if displaytype == "simple" {
-		cols, data, err := GetProcesslist(mydb)
-		if err != nil {
-			return err
+	if opts.Mode == ModeSimple {
+		for _, srv := range servers.List() {
+			if err := srv.LoadInfo(); err != nil {
+				return err
+			}
+			if reason := srv.Unavailable(ScreenProcesslist); len(reason) > 0 {
+				return fmt.Errorf("%s: the processlist is not available on this server because %s", srv.Name, reason)
+			}
+			result, err := GetProcesslist(srv.DB, srv.Capabilities)
+			if err != nil {
+				return err
+			}
+			if servers.Len() > 1 {
+				fmt.Printf("%s\n", srv.Name)
+			}
+			DisplaySimple(result.Columns, result.Strings())
 		}
-		DisplaySimple(cols, data)
 	} else {
-		err := DisplayProcesslist(mydb)
+		err := DisplayProcesslist(servers, opts)
 		if err != nil {
 			ExitWithError(err)
 		}
@@ -44,36 +55,58 @@ func Processlist(mydb *sql.DB, displaytype string) error {
 	return nil
 }
 
-func GetProcesslist(mydb *sql.DB) ([]string, [][]string, error) {
+// GetProcesslist reads performance_schema, using the formatting functions
+// of the sys schema and format_pico_time() when the server has them. MariaDB
+// and the servers without performance_schema use information_schema.
+func GetProcesslist(mydb db.Querier, caps db.Capabilities) (*db.Result, error) {
+	return db.Query(mydb, processlistStatement(caps))
+}
+
+// processlistStatement returns the processlist query of the server
+func processlistStatement(caps db.Capabilities) string {
+	if caps.MariaDB || !caps.PerformanceSchema {
+		return queries(caps).processlist
+	}
+	format_statement := "pps.PROCESSLIST_INFO"
+	if caps.SysSchema {
+		format_statement = "sys.format_statement(pps.PROCESSLIST_INFO)"
+	}
+	format_time := "%s"
+	if caps.FormatPicoTime() {
+		format_time = "format_pico_time(%s)"
+	} else if caps.SysSchema {
+		format_time = "sys.format_time(%s)"
+	}
 	stmt := `select pps.PROCESSLIST_COMMAND AS command,
                                   pps.THREAD_ID AS thd_id, pps.PROCESSLIST_ID AS conn_id,
                                   conattr_pid.ATTR_VALUE AS pid, pps.PROCESSLIST_STATE AS state,
                                   if((pps.NAME in ('thread/sql/one_connection','thread/thread_pool/tp_one_connection')),
                                    concat(pps.PROCESSLIST_USER,'@',pps.PROCESSLIST_HOST),
                                    replace(pps.NAME,'thread/','')) AS user,
-                                  pps.PROCESSLIST_DB AS db, sys.format_statement(pps.PROCESSLIST_INFO) AS current_statement,
-                                  if(isnull(esc.END_EVENT_ID), format_pico_time(esc.TIMER_WAIT),NULL) AS statement_latency,
-                                  format_pico_time(esc.LOCK_TIME) AS lock_latency,
-                                  if(isnull(esc.END_EVENT_ID),esc.TIMER_WAIT,0) AS sort_time
-                            from (performance_schema.threads pps
+                                  pps.PROCESSLIST_DB AS db, ` + format_statement + ` AS current_statement,
+                                  if(isnull(esc.END_EVENT_ID), ` + fmt.Sprintf(format_time, "esc.TIMER_WAIT") + `,NULL) AS statement_latency,
+                                  ` + fmt.Sprintf(format_time, "esc.LOCK_TIME") + ` AS lock_latency,
+                                  if(isnull(esc.END_EVENT_ID),esc.TIMER_WAIT,0) AS sort_time,
+                                  esc.LOCK_TIME AS lock_time, esc.ROWS_EXAMINED AS rows_examined, mem.memory, esc.DIGEST AS digest,
+                                  case when pps.PROCESSLIST_COMMAND like 'Binlog Dump%' or pps.NAME like 'thread/sql/slave_%'
+                                         or pps.NAME like 'thread/sql/replica_%' or pps.NAME like 'thread/group_rpl/%' then 'replication'
+                                       when pps.NAME not in ('thread/sql/one_connection','thread/thread_pool/tp_one_connection') then 'background'
+                                       else 'foreground' end AS thread_type,
+                                  pps.PROCESSLIST_ID = connection_id() AS own, pps.PROCESSLIST_INFO AS full_statement
+                            from ((performance_schema.threads pps
                             left join performance_schema.events_statements_current esc
                                 on (pps.THREAD_ID = esc.THREAD_ID))
+                            left join (select THREAD_ID, sum(CURRENT_NUMBER_OF_BYTES_USED) AS memory
+                                         from performance_schema.memory_summary_by_thread_by_event_name
+                                        group by THREAD_ID) mem
+                                on (pps.THREAD_ID = mem.THREAD_ID))
 							left join performance_schema.session_connect_attrs conattr_pid
         						 on((conattr_pid.PROCESSLIST_ID = pps.PROCESSLIST_ID) and (conattr_pid.ATTR_NAME = '_pid'))
                             where pps.PROCESSLIST_ID is not null
                               and pps.PROCESSLIST_COMMAND <> 'Daemon'
                             order by sort_time desc
                         `
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return cols, data, nil
+	return stmt
 }
 
 func periodic(ctx context.Context, interval time.Duration, fn func() error) error {
@@ -91,52 +124,212 @@ func periodic(ctx context.Context, interval time.Duration, fn func() error) erro
 	}
 }
 
-func DisplayProcesslistContent(mydb *sql.DB, main_window *text.Text) error {
-	_, data, err := GetProcesslist(mydb)
+// ProcesslistView is how the user chose to display the processlist, it is
+// kept across the refreshes and the server switches.
+type ProcesslistView struct {
+	Sort   ProcesslistSort
+	Filter ProcesslistFilter
+	Hide   ProcesslistHide
+	Group  ProcesslistGroup
+
+	mutex sync.Mutex
+	// last is the last processlist read, displayed again when the sort or
+	// the filter change
+	last *db.Result
+	// rows are the rows displayed after the filter and the sort, page the
+	// number of them fitting in the window. threads are the threads behind
+	// them, the rows being groups when the processlist is grouped.
+	rows    []db.Row
+	threads []db.Row
+	page    int
+	// cursor is the position of the selected row in rows and offset the
+	// position of the first row displayed
+	cursor int
+	offset int
+	// selected is the connection id of the selected thread, the thread id
+	// is missing from information_schema
+	selected string
+}
+
+// Forget drops the last processlist read, when it belongs to a server that
+// is no longer displayed
+func (view *ProcesslistView) Forget() {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	view.last = nil
+	view.rows = nil
+	view.threads = nil
+	view.cursor, view.offset, view.selected = 0, 0, ""
+	view.Hide.Forget()
+}
+
+// processlistTitle returns the border title of the processlist with the
+// active filter and grouping
+func processlistTitle(view *ProcesslistView) string {
+	title := "Processlist"
+	if field := view.Group.Field(); len(field) > 0 {
+		title += fmt.Sprintf(" [grouped by %s, <d> to open a group]", field)
+	}
+	if filter := view.Filter.String(); len(filter) > 0 {
+		title += fmt.Sprintf(" [filter: %s]", filter)
+	}
+	return title + " (ESC to quit, ? to help)"
+}
+
+// processlist_format is the layout of the lines of the processlist, the
+// columns being wide enough for their title and the sort mark
+const processlist_format = "%-7v %-5v %-5v %-7v %-25v %-20v %-12v %10v %11v %10v %10v %-65v\n"
+
+func DisplayProcesslistContent(mydb db.Querier, caps db.Capabilities, main_window *ProcesslistWindow, footer *text.Text, view *ProcesslistView) error {
+	result, err := GetProcesslist(mydb, caps)
 	if err != nil {
 		return err
 	}
+	view.Hide.See(result)
+	view.mutex.Lock()
+	view.last = result
+	view.mutex.Unlock()
+	return view.Redraw(main_window, footer)
+}
+
+// Redraw displays the last processlist read with the current sort, filter,
+// grouping and hidden threads, without querying the server again. Only the
+// rows around the selected thread fitting in the window are written. The
+// footer gets the number of rows hidden.
+func (view *ProcesslistView) Redraw(main_window *ProcesslistWindow, footer *text.Text) error {
+	view.mutex.Lock()
+	last := view.last
+	view.mutex.Unlock()
+	if last == nil {
+		return nil
+	}
+	// the filter and the sort work on a copy of the rows
+	result := &db.Result{Columns: last.Columns, Rows: append([]db.Row{}, last.Rows...)}
+	removed := view.Hide.Apply(result)
+	filtered := view.Filter.Apply(result)
+	footer.Reset()
+	if line := view.Hide.Footer(removed, filtered); len(line) > 0 {
+		footer.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(31))))
+	}
+	order := &view.Sort
+	order.Apply(result)
+	threads := result.Rows
+	if field := view.Group.Field(); len(field) > 0 {
+		return view.redrawGroups(main_window, field, threads)
+	}
+	// the header takes the first line
+	page := main_window.Height() - 1
+	if page < 1 {
+		page = len(result.Rows)
+	}
+	offset, cursor := view.place(result.Rows, threads, page)
 	main_window.Reset()
-	header := fmt.Sprintf("%-7v %-5v %-5v %-7v %-25v %-20v %-12v %10v %10v %-65v\n",
-		"Cmd", "Thd", "Conn", "Pid", "State", "User", "Db", "Time", "Lock Time", "Query")
+	header := fmt.Sprintf(processlist_format,
+		order.Header("Cmd"), "Thd", "Conn", "Pid", "State", order.Header("User"), order.Header("Db"),
+		order.Header("Time"), order.Header("Lock Time"), order.Header("Rows"), order.Header("Memory"), "Query")
 	if err := main_window.Write(header, text.WriteCellOpts(cell.Bold())); err != nil {
 		return err
 	}
 	var color int
-	for _, row := range data {
-		line := fmt.Sprintf("%-7v %-5v %-5v %-7v %-25v %-20v %-12v %10v %10v %-65v\n",
-			ChunkString(row[0], 7),
-			ChunkString(row[1], 5),
-			ChunkString(row[2], 5),
-			ChunkString(row[3], 7),
-			ChunkString(row[4], 25),
-			ChunkString(row[5], 20),
-			ChunkString(row[6], 12),
-			ChunkString(row[8], 10),
-			ChunkString(row[9], 10),
-			row[7])
-		col_value, _ := strconv.Atoi(row[10])
-		switch {
-		case col_value > 60_000_000_000_000:
-			color = 9 // red after 1min
-		case col_value > 30_000_000_000_000:
-			color = 172 // orange after 30sec
-		case col_value > 10_000_000_000_000:
-			color = 2 // green after 10sec
-		case col_value > 5_000_000_000_000:
-			color = 6 // blue after 5sec
-		default:
-			color = 15 // white
+	end := offset + page
+	if end > len(result.Rows) {
+		end = len(result.Rows)
+	}
+	for i := offset; i < end; i++ {
+		row := result.Rows[i]
+		memory := ""
+		if bytes, ok := row.Get("memory").Int64(); ok {
+			memory = FormatBytes(int(bytes))
+		}
+		line := fmt.Sprintf(processlist_format,
+			ChunkString(row.Get("command").String(), 7),
+			ChunkString(row.Get("thd_id").String(), 5),
+			ChunkString(row.Get("conn_id").String(), 5),
+			ChunkString(row.Get("pid").String(), 7),
+			ChunkString(row.Get("state").String(), 25),
+			ChunkString(row.Get("user").String(), 20),
+			ChunkString(row.Get("db").String(), 12),
+			ChunkString(row.Get("statement_latency").String(), 10),
+			ChunkString(row.Get("lock_latency").String(), 11),
+			ChunkString(row.Get("rows_examined").String(), 10),
+			memory,
+			row.Get("current_statement").String())
+		if i == end-1 {
+			// a last empty line would not fit in the window
+			line = strings.TrimSuffix(line, "\n")
+		}
+		// picoseconds, 0 when no statement is running
+		sort_time, _ := row.Get("sort_time").Uint64()
+		color = latencyColor(sort_time)
+		if i == cursor {
+			main_window.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(color)), cell.Inverse()))
+			continue
 		}
 		main_window.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(color))))
 	}
 	return nil
 }
 
-func BackToMainView(c *container.Container, top_window *text.Text, main_window *text.Text,
+// redrawGroups displays the groups of the threads on the field
+func (view *ProcesslistView) redrawGroups(main_window *ProcesslistWindow, field string, threads []db.Row) error {
+	groups := groupProcesslist(field, &db.Result{Rows: threads})
+	page := main_window.Height() - 1
+	if page < 1 {
+		page = len(groups.Rows)
+	}
+	offset, cursor := view.place(groups.Rows, threads, page)
+	main_window.Reset()
+	if err := main_window.Write(groupHeader(field), text.WriteCellOpts(cell.Bold())); err != nil {
+		return err
+	}
+	end := offset + page
+	if end > len(groups.Rows) {
+		end = len(groups.Rows)
+	}
+	for i := offset; i < end; i++ {
+		row := groups.Rows[i]
+		line := groupLine(row)
+		if i == end-1 {
+			line = strings.TrimSuffix(line, "\n")
+		}
+		longest, _ := row.Get("max_time").Uint64()
+		opts := []cell.Option{cell.FgColor(cell.ColorNumber(latencyColor(longest)))}
+		if i == cursor {
+			opts = append(opts, cell.Inverse())
+		}
+		main_window.Write(line, text.WriteCellOpts(opts...))
+	}
+	return nil
+}
+
+// latencyColor returns the color of a thread, or of a group, running a
+// statement for ps picoseconds
+func latencyColor(ps uint64) int {
+	switch {
+	case ps > 60_000_000_000_000:
+		return 9 // red after 1min
+	case ps > 30_000_000_000_000:
+		return 172 // orange after 30sec
+	case ps > 10_000_000_000_000:
+		return 2 // green after 10sec
+	case ps > 5_000_000_000_000:
+		return 6 // blue after 5sec
+	}
+	return 15 // white
+}
+
+func newQPSGraph() (*sparkline.SparkLine, error) {
+	return sparkline.New(
+		sparkline.Color(cell.ColorBlue),
+		sparkline.Label("QPS"),
+	)
+}
+
+func BackToMainView(c *container.Container, top_window *text.Text, main_window *ProcesslistWindow,
 	tlg *barchart.BarChart, trg *sparkline.SparkLine, current_mode string) error {
 	if current_mode == "help" || current_mode == "thread_details" || current_mode ==
-		"innodb" || current_mode == "memory" || current_mode == "replication" {
+		"innodb" || current_mode == "memory" || current_mode == "replication" || current_mode == "trace" ||
+		current_mode == "top_queries" || current_mode == "statement" {
 		c.Update("main_container", container.Clear())
 		c.Update("dyn_top_container", container.Clear())
 	} else {
@@ -185,17 +378,29 @@ func BackToMainView(c *container.Container, top_window *text.Text, main_window *
 	return nil
 }
 
-func DisplayProcesslist(mydb *sql.DB) error {
+func DisplayProcesslist(servers *Servers, opts Options) error {
 
 	show_processlist := true
 	processlist_drawing := false
 	waiting_input := false
 	current_mode := "processlist"
 	thread_id := "0"
+	view := &ProcesslistView{}
+	view.Filter.Named = opts.Filters
+	top_queries := NewTopQueries()
+	// explain_sample is the sample query of a digest explained from the Top
+	// Queries screen, nil when the EXPLAIN is the one of a thread
+	var explain_sample *QuerySample
+	// statement is the one shown by the statement viewer, opened from the
+	// screen of statement_from
+	var statement *Statement
+	statement_formatted := true
+	statement_from := "processlist"
 
 	var c *container.Container
-	var status map[string]string
+	var status map[string]db.Value
 	var old_values []int
+	var status_server *Server
 	status = nil
 
 	t, err := tcell.New()
@@ -203,6 +408,8 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		return err
 	}
 	defer t.Close()
+	// tcell draws on the standard output
+	clipboard := NewClipboardTerminal(t, os.Stdout)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	innotop, err := text.New()
@@ -232,6 +439,16 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		return err
 	}
 
+	// the processlist is drawn by a wrapper of the main window
+	list_window := NewProcesslistWindow(main_window)
+
+	// footer with the number of threads hidden from the processlist
+	footer, err := text.New()
+	if err != nil {
+		cancel()
+		return err
+	}
+
 	// graph on top left
 
 	tlg, err := barchart.New(
@@ -262,15 +479,137 @@ func DisplayProcesslist(mydb *sql.DB) error {
 	}
 
 	// graph on top right
-	trg, err := sparkline.New(
-		sparkline.Color(cell.ColorBlue),
-		sparkline.Label("QPS"),
-	)
+	trg, err := newQPSGraph()
 	if err != nil {
 		cancel()
 		return err
 	}
 
+	// openStatement shows the full statement of the thread in the statement
+	// viewer, the error is shown below the screen it was opened from
+	openStatement := func(thread_id_in string, conn_id string) {
+		srv := servers.Current()
+		stmt, err := GetStatement(srv.DB, srv.Capabilities, thread_id_in, conn_id)
+		if err == nil {
+			statement = &stmt
+			show_processlist = false
+			current_mode = "statement"
+			err = DisplayStatement(c, stmt, statement_formatted)
+			if err == nil {
+				return
+			}
+		}
+		error_msg.Reset()
+		id := thread_id_in
+		if len(id) == 0 {
+			id = conn_id
+		}
+		error_msg.Write(fmt.Sprintf("the statement of thread '%s' cannot be retrieved: %v", id, err),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+		c.Update("bottom_container", container.PlaceWidget(error_msg))
+		if statement_from == "processlist" {
+			show_processlist = true
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+			current_mode = "processlist"
+			thread_id = "0"
+		}
+	}
+
+	// openKill shows the threads to kill and waits for the confirmation
+	var kill_dialog *KillDialog
+	openKill := func(dialog *KillDialog) {
+		kill_dialog = dialog
+		show_processlist = false
+		current_mode = "kill_dialog"
+		c.Update("bottom_container", container.Clear())
+		c.Update("main_container", container.PlaceWidget(main_window))
+		c.Update("main_container", container.BorderTitle(dialog.Title()))
+		dialog.Display(main_window)
+	}
+
+	// killThread asks the confirmation of the kill of a thread, but not of a
+	// connection of innotopgo
+	killThread := func(row db.Row) bool {
+		if view.Hide.Own(row) {
+			error_msg.Reset()
+			error_msg.Write(fmt.Sprintf("thread '%s' is a connection of innotopgo", row.Get("conn_id").String()),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+			c.Update("bottom_container", container.PlaceWidget(error_msg))
+			return false
+		}
+		openKill(&KillDialog{Rows: []db.Row{row}})
+		return true
+	}
+
+	// openThread opens the screen of the current mode for the thread, or
+	// the confirmation of its kill
+	openThread := func(thread_id_in string) {
+		thread_id = thread_id_in
+		explain_sample = nil
+		if current_mode == "statement" {
+			// a typed id is a connection id for information_schema
+			openStatement(thread_id_in, "")
+		} else if current_mode == "explain_normal" {
+			show_processlist = false
+			main_window.Reset()
+			top_window.Reset()
+			err := DisplayExplain(ctx, servers.DB(), c, top_window, main_window, thread_id, "NORMAL")
+			if err != nil {
+				error_msg.Reset()
+				error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+				show_processlist = true
+				BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+				current_mode = "processlist"
+				thread_id = "0"
+			}
+		} else if current_mode == "locking" {
+			show_processlist = false
+			main_window.Reset()
+			top_window.Reset()
+			err := DisplayLocking(ctx, servers.DB(), c, top_window, main_window, thread_id)
+			if err != nil {
+				error_msg.Reset()
+				error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+				show_processlist = true
+				BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+				current_mode = "processlist"
+				thread_id = "0"
+			}
+		} else if current_mode == "kill" {
+			// the typed id must be a thread of the processlist displayed
+			if row, ok := view.Thread(thread_id_in); !ok {
+				error_msg.Reset()
+				error_msg.Write(fmt.Sprintf("thread '%s' is not in the processlist", thread_id_in),
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+			} else if killThread(row) {
+				return
+			}
+			c.Update("main_container", container.Focused())
+			show_processlist = true
+			current_mode = "processlist"
+			thread_id = "0"
+		} else if current_mode == "thread_details" {
+			main_window.Reset()
+			top_window.Reset()
+			err = DisplayThreadDetails(servers.DB(), c, thread_id)
+			if err != nil {
+				error_msg.Reset()
+				error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+				show_processlist = true
+				BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+				current_mode = "processlist"
+				thread_id = "0"
+			}
+		}
+	}
+
 	// input box at the bottom
 	bottom_input, err := textinput.New(
 		textinput.MaxWidthCells(4),
@@ -289,66 +628,7 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				thread_id = "0"
 				return nil
 			}
-			thread_id = thread_id_in
-			if current_mode == "explain_normal" {
-				show_processlist = false
-				main_window.Reset()
-				top_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "NORMAL")
-				if err != nil {
-					error_msg.Reset()
-					error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
-						text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-					c.Update("bottom_container", container.PlaceWidget(error_msg))
-					show_processlist = true
-					BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
-					current_mode = "processlist"
-					thread_id = "0"
-				}
-			} else if current_mode == "locking" {
-				show_processlist = false
-				main_window.Reset()
-				top_window.Reset()
-				err := DisplayLocking(ctx, mydb, c, top_window, main_window, thread_id)
-				if err != nil {
-					error_msg.Reset()
-					error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
-						text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-					c.Update("bottom_container", container.PlaceWidget(error_msg))
-					show_processlist = true
-					BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
-					current_mode = "processlist"
-					thread_id = "0"
-				}
-			} else if current_mode == "kill" {
-				err = KillQuery(mydb, thread_id)
-				if err != nil {
-					error_msg.Reset()
-					error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
-						text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-					c.Update("bottom_container", container.PlaceWidget(error_msg))
-				} else {
-					c.Update("bottom_container", container.Clear())
-				}
-				c.Update("main_container", container.Focused())
-				show_processlist = true
-				current_mode = "processlist"
-				thread_id = "0"
-			} else if current_mode == "thread_details" {
-				main_window.Reset()
-				top_window.Reset()
-				err = DisplayThreadDetails(mydb, c, thread_id)
-				if err != nil {
-					error_msg.Reset()
-					error_msg.Write(fmt.Sprintf("Thread_id '%s' cannot be retrieved", thread_id_in),
-						text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-					c.Update("bottom_container", container.PlaceWidget(error_msg))
-					show_processlist = true
-					BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
-					current_mode = "processlist"
-					thread_id = "0"
-				}
-			}
+			openThread(thread_id_in)
 			waiting_input = false
 			return nil
 		}),
@@ -358,55 +638,141 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		return err
 	}
 
-	_, data, err := db.GetServerInfo(mydb)
+	// redraw displays the processlist again right away, after a change of
+	// its order for example
+	redraw := func() {
+		if processlist_drawing {
+			return
+		}
+		processlist_drawing = true
+		err := view.Redraw(list_window, footer)
+		if err != nil {
+			cancel()
+			t.Close()
+			ExitWithError(err)
+		}
+		processlist_drawing = false
+	}
+
+	// filter input at the bottom, an empty filter shows all the threads
+	filter_input, err := textinput.New(
+		textinput.Label("Filter: ", cell.FgColor(cell.ColorNumber(31))),
+		textinput.PlaceHolder("user=app cmd!=Sleep stmt~/FOR UPDATE/ time>5s, or a saved filter"),
+		textinput.ClearOnSubmit(),
+		textinput.OnSubmit(func(filter_in string) error {
+			if err := view.Filter.Set(filter_in); err != nil {
+				error_msg.Reset()
+				error_msg.Write(err.Error(), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+			} else {
+				c.Update("bottom_container", container.Clear())
+			}
+			c.Update("main_container", container.BorderTitle(processlistTitle(view)))
+			c.Update("main_container", container.Focused())
+			current_mode = "processlist"
+			waiting_input = false
+			redraw()
+			return nil
+		}),
+	)
 	if err != nil {
 		cancel()
 		return err
 	}
-	innotop.Write("Inno", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
-	innotop.Write("Top", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-	innotop.Write(" Go ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
-	innotop.Write(version(), text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
-	innotop.Write(" | ", text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Bold()))
-
-	var mysql_version string
-	var mysql_brand string
-	for _, row := range data {
-		line := fmt.Sprintf("%s %s ", row[0], row[1])
-		mysql_brand = row[0]
-		mysql_version = row[1]
-		innotop.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(172)), cell.Italic()))
-		line = fmt.Sprintf("[%s:%s]", row[2], row[3])
-		innotop.Write(line, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7)), cell.FgColor(cell.ColorNumber(31)), cell.Italic()))
-	}
-	innotop.Write(strings.Repeat(" ", 200), text.WriteCellOpts(cell.BgColor(cell.ColorNumber(7))))
-	if !strings.HasPrefix(mysql_version, "8") {
-		cancel()
-		fmt.Printf("\n\n... Sorry %v %v is not supported ...", mysql_brand, mysql_version)
-		time.Sleep(3 * time.Second)
-		return nil
+
+	for _, srv := range servers.List() {
+		err = srv.LoadInfo()
+		if err != nil {
+			cancel()
+			return err
+		}
+		if !srv.Capabilities.Supported() {
+			cancel()
+			fmt.Printf("\n\n... Sorry %v %v (%v) is not supported ...", srv.Brand, srv.Version, srv.Name)
+			time.Sleep(3 * time.Second)
+			return nil
+		}
+	}
+	// the statements are traced with the screen issuing them
+	db.SetTraceScreen(func() string {
+		return current_mode
+	})
+	servers.Interval = opts.Interval
+	servers.OnRefresh = func(srv *Server) {
+		DisplayHeader(innotop, servers)
+	}
+	DisplayHeader(innotop, servers)
+	servers.OnSwitch = func(srv *Server) {
+		DisplayHeader(innotop, servers)
+		view.Forget()
+		// the QPS history belongs to the previous server
+		new_trg, err := newQPSGraph()
+		if err == nil {
+			trg = new_trg
+			c.Update("top_right_graph", container.PlaceWidget(trg))
+		}
 	}
 	main_window.Write("\n\n... please wait...", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
-	go periodic(ctx, 1*time.Second, func() error {
-		if show_processlist {
-			//top_window.Reset()
-			status, old_values, err = DisplayStatus(mydb, top_window, tlg, trg, status, old_values)
+	// displayUnavailable replaces the content of the main window by the
+	// reason why the screen cannot be displayed for the current server
+	displayUnavailable := func(screen string, reason string) {
+		main_window.Reset()
+		main_window.Write(fmt.Sprintf("\n\n%s is not available on this server because %s.", screen_titles[screen], reason),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+	}
+	refresh := servers.Refresh(func(srv *Server) error {
+		if srv != status_server {
+			// no delta between two different servers
+			status = nil
+			old_values = nil
+			status_server = srv
+		}
+		//top_window.Reset()
+		new_status, new_values, err := DisplayStatus(srv.DB, srv.Capabilities, top_window, tlg, trg, status, old_values)
+		if err != nil {
+			return err
+		}
+		status, old_values = new_status, new_values
+		if reason := srv.Unavailable(ScreenProcesslist); len(reason) > 0 {
+			displayUnavailable(ScreenProcesslist, reason)
+		} else if !processlist_drawing {
+			processlist_drawing = true
+			err = DisplayProcesslistContent(srv.DB, srv.Capabilities, list_window, footer, view)
+			processlist_drawing = false
 			if err != nil {
+				return err
+			}
+			if c != nil && show_processlist {
+				// BackToMainView restores the title without the filter
+				c.Update("main_container", container.BorderTitle(processlistTitle(view)))
+			}
+		}
+		return nil
+	})
+	go periodic(ctx, opts.Interval, func() error {
+		if show_processlist {
+			if err := refresh(); err != nil {
 				cancel()
 				t.Close()
 				ExitWithError(err)
 			}
-			if !processlist_drawing {
-				processlist_drawing = true
-				err = DisplayProcesslistContent(mydb, main_window)
-				if err != nil {
-					cancel()
-					t.Close()
-					ExitWithError(err)
-				}
-				processlist_drawing = false
-			}
-
+		} else {
+			// the counts only apply to the processlist
+			footer.Reset()
+		}
+		return nil
+	})
+	// keep the header up to date with the state of the connection
+	disconnected := false
+	var trace_window *text.Text
+	go periodic(ctx, time.Second, func() error {
+		lost, _ := servers.Current().Disconnected()
+		if lost || disconnected || servers.Replay() != nil {
+			DisplayHeader(innotop, servers)
+		}
+		disconnected = lost
+		if current_mode == "trace" && trace_window != nil {
+			WriteTrace(trace_window)
 		}
 		return nil
 	})
@@ -454,16 +820,25 @@ func DisplayProcesslist(mydb *sql.DB) error {
 								container.Border(linestyle.Light),
 								container.ID("main_container"),
 								container.BorderTitle("Processlist (ESC to quit, ? to help)"),
-								container.PlaceWidget(main_window),
+								container.PlaceWidget(list_window),
 								container.FocusedColor(cell.ColorNumber(15)),
 							),
 							container.SplitFixed(8),
 						),
 					),
 					container.Bottom(
-						container.ID("bottom_container"),
-						container.AlignHorizontal(align.HorizontalLeft),
-						container.Clear(),
+						container.SplitVertical(
+							container.Left(
+								container.ID("bottom_container"),
+								container.AlignHorizontal(align.HorizontalLeft),
+								container.Clear(),
+							),
+							container.Right(
+								container.ID("footer_container"),
+								container.PlaceWidget(footer),
+							),
+							container.SplitPercent(65),
+						),
 					),
 					container.SplitPercent(99),
 				),
@@ -478,17 +853,138 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		return err
 	}
 
+	// unavailable shows a panel instead of the screen when the current server
+	// cannot display it
+	unavailable := func(screen string) bool {
+		reason := servers.Current().Unavailable(screen)
+		if len(reason) == 0 {
+			return false
+		}
+		if current_mode != "processlist" {
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+		}
+		show_processlist = false
+		current_mode = "unavailable"
+		displayUnavailable(screen, reason)
+		c.Update("main_container", container.BorderTitle(screen_titles[screen]+" (<-- <Backspace> to return to Processlist)"))
+		return true
+	}
+
+	// selectThread runs the action of the mode on the selected thread, the
+	// thread id is asked when no thread is selected
+	selectThread := func(mode string) {
+		current_mode = mode
+		if row, ok := view.Selected(); ok && !row.Get("thd_id").IsNull() {
+			openThread(row.Get("thd_id").String())
+			return
+		}
+		waiting_input = true
+		c.Update("bottom_container", container.PlaceWidget(bottom_input))
+		c.Update("bottom_container", container.Focused())
+	}
+
+	// explain shows the plan of the thread or of the sample query in the
+	// format of explain_type
+	explain := func(explain_type string) error {
+		if explain_sample != nil {
+			return DisplayExplainQuery(ctx, servers.DB(), c, top_window, main_window, explain_sample.Db, explain_sample.Text, explain_type)
+		}
+		return DisplayExplain(ctx, servers.DB(), c, top_window, main_window, thread_id, explain_type)
+	}
+
+	// openTopQueries shows the Top Queries screen until it is left, to the
+	// processlist or to the EXPLAIN of a sample query
+	openTopQueries := func() {
+		if unavailable(ScreenTopQueries) {
+			return
+		}
+		show_processlist = false
+		current_mode = "top_queries"
+		k2, sample, err := DisplayTopQueries(servers, top_queries, c, t, opts.Interval)
+		if err != nil {
+			cancel()
+			t.Close()
+			ExitWithError(err)
+		}
+		if k2 == keyboard.KeyEsc {
+			cancel()
+		}
+		if k2 == 'e' {
+			explain_sample = &sample
+			current_mode = "explain_normal"
+			main_window.Reset()
+			top_window.Reset()
+			if len(sample.Text) == 0 {
+				err = errors.New("the digest has no sample query, it requires MySQL 8.0.3 or later")
+			} else {
+				err = explain("NORMAL")
+			}
+			if err == nil {
+				return
+			}
+			explain_sample = nil
+			error_msg.Reset()
+			error_msg.Write(fmt.Sprintf("the sample query cannot be explained: %v", err),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+			c.Update("bottom_container", container.PlaceWidget(error_msg))
+		}
+		show_processlist = true
+		BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+		current_mode = "processlist"
+		thread_id = "0"
+		// the server was switched to one without this screen
+		unavailable(ScreenTopQueries)
+	}
+
+	list_window.OnClick = func(line int) {
+		if current_mode == "processlist" {
+			view.Click(line)
+			redraw()
+		}
+	}
+
 	quitter := func(k *terminalapi.Keyboard) {
 		if k.Key == keyboard.KeyEsc || k.Key == keyboard.KeyCtrlC {
 			cancel()
+		} else if current_mode == "filter" {
+			// the keys are typed in the filter
+			return
+		} else if !waiting_input && servers.HandleKey(k) {
+			// the thread shown belongs to the previous server
+			if current_mode == "thread_details" || current_mode == "locking" || current_mode == "statement" ||
+				current_mode == "unavailable" || current_mode == "kill_dialog" || strings.HasPrefix(current_mode, "explain_") {
+				explain_sample = nil
+				show_processlist = true
+				BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+				current_mode = "processlist"
+				thread_id = "0"
+			}
 		} else if k.Key == '?' {
 			show_processlist = false
 			current_mode = "help"
 			DisplayHelp(c)
+		} else if k.Key == 'S' {
+			if current_mode == "processlist" {
+				show_processlist = false
+				current_mode = "trace"
+				trace_window, err = DisplayTrace(c)
+				if err != nil {
+					cancel()
+					t.Close()
+					ExitWithError(err)
+				}
+			}
+		} else if k.Key == 'T' {
+			if current_mode == "processlist" {
+				openTopQueries()
+			}
 		} else if k.Key == 'm' || k.Key == 'M' {
+			if unavailable(ScreenMemory) {
+				return
+			}
 			show_processlist = false
 			current_mode = "memory"
-			k2, err := DisplayMemory(mydb, c, t)
+			k2, err := DisplayMemory(servers, c, t, opts.Interval)
 			if err != nil {
 				cancel()
 				t.Close()
@@ -498,29 +994,54 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				cancel()
 			}
 			show_processlist = true
-			BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
 			current_mode = "processlist"
 			thread_id = "0"
-			} else if k.Key == 'r' || k.Key == 'R' {
-				show_processlist = false
-				current_mode = "replication"
-				k2, err := DisplayReplication(mydb, c, t)
-				if err != nil {
-					cancel()
-					t.Close()
-					ExitWithError(err)
-				}
-				if k2 == keyboard.KeyEsc {
-					cancel()
-				}
-				show_processlist = true
-				BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
-				current_mode = "processlist"
-				thread_id = "0"
+			// the server was switched to one without this screen
+			unavailable(ScreenMemory)
+		} else if (k.Key == 'i' || k.Key == 'b' || k.Key == 'o' || k.Key == 'c') && current_mode == "processlist" && !waiting_input {
+			switch k.Key {
+			case 'i':
+				view.Hide.Toggle(thread_idle)
+			case 'b':
+				view.Hide.Toggle(thread_background)
+			case 'o':
+				view.Hide.Toggle(thread_own)
+			case 'c':
+				view.Hide.Toggle(thread_replication)
+			}
+			redraw()
+		} else if k.Key == 'r' && current_mode == "processlist" && !waiting_input {
+			view.Sort.Reverse()
+			redraw()
+		} else if k.Key == 'r' || k.Key == 'R' {
+			if unavailable(ScreenReplication) {
+				return
+			}
+			show_processlist = false
+			current_mode = "replication"
+			k2, err := DisplayReplication(servers, c, t, opts.Interval)
+			if err != nil {
+				cancel()
+				t.Close()
+				ExitWithError(err)
+			}
+			if k2 == keyboard.KeyEsc {
+				cancel()
+			}
+			show_processlist = true
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
+			current_mode = "processlist"
+			thread_id = "0"
+			// the server was switched to one without this screen
+			unavailable(ScreenReplication)
 		} else if k.Key == 'i' || k.Key == 'I' {
+			if unavailable(ScreenInnoDB) {
+				return
+			}
 			show_processlist = false
 			current_mode = "innodb"
-			k2, err := DisplayInnoDB(mydb, c, t)
+			k2, err := DisplayInnoDB(servers, c, t, opts.Interval)
 			if err != nil {
 				cancel()
 				t.Close()
@@ -530,27 +1051,32 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				cancel()
 			}
 			show_processlist = true
-			BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
 			current_mode = "processlist"
 			thread_id = "0"
+			// the server was switched to one without this screen
+			unavailable(ScreenInnoDB)
 		} else if k.Key == 'l' || k.Key == 'L' {
 			if current_mode == "processlist" {
-				waiting_input = true
-				c.Update("bottom_container", container.PlaceWidget(bottom_input))
-				c.Update("bottom_container", container.Focused())
-				current_mode = "locking"
+				if unavailable(ScreenLocking) {
+					return
+				}
+				selectThread("locking")
 			}
 		} else if k.Key == 'e' {
 			if current_mode == "processlist" {
-				waiting_input = true
-				c.Update("bottom_container", container.PlaceWidget(bottom_input))
-				c.Update("bottom_container", container.Focused())
-				current_mode = "explain_normal"
+				if unavailable(screen_explain) {
+					return
+				}
+				selectThread("explain_normal")
 			}
 		} else if k.Key == 'E' {
+			if unavailable(ScreenErrorlog) {
+				return
+			}
 			show_processlist = false
 			current_mode = "error_log"
-			k2, err := DisplayErrorlog(mydb, c, t)
+			k2, err := DisplayErrorlog(servers, c, t, opts.Interval)
 			if err != nil {
 				cancel()
 				t.Close()
@@ -560,33 +1086,131 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				cancel()
 			}
 			show_processlist = true
-			BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
+			BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
 			current_mode = "processlist"
 			thread_id = "0"
+			// the server was switched to one without this screen
+			unavailable(ScreenErrorlog)
+		} else if k.Key == 'd' && current_mode == "processlist" && !waiting_input && len(view.Group.Field()) > 0 {
+			// the threads of the selected group
+			if row, ok := view.SelectedGroup(); ok {
+				if err := view.Group.Open(row, &view.Filter); err != nil {
+					error_msg.Reset()
+					error_msg.Write(err.Error(), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+					c.Update("bottom_container", container.PlaceWidget(error_msg))
+					return
+				}
+				c.Update("main_container", container.BorderTitle(processlistTitle(view)))
+				redraw()
+			}
+		} else if k.Key == 'g' && current_mode == "processlist" && !waiting_input {
+			view.Group.Next()
+			c.Update("main_container", container.BorderTitle(processlistTitle(view)))
+			redraw()
 		} else if k.Key == 'd' || k.Key == 'D' {
 			if current_mode == "processlist" {
+				if unavailable(screen_thread_details) {
+					return
+				}
 				show_processlist = false
-				current_mode = "thread_details"
+				selectThread("thread_details")
+			}
+		} else if k.Key == 'v' || k.Key == 'V' {
+			if current_mode == "processlist" && !waiting_input {
+				statement_from = "processlist"
+				if row, ok := view.Selected(); ok {
+					openStatement(row.Get("thd_id").String(), row.Get("conn_id").String())
+					return
+				}
+				current_mode = "statement"
 				waiting_input = true
 				c.Update("bottom_container", container.PlaceWidget(bottom_input))
 				c.Update("bottom_container", container.Focused())
+			} else if current_mode == "thread_details" {
+				statement_from = "thread_details"
+				openStatement(thread_id, "")
+			}
+		} else if current_mode == "statement" && (k.Key == 'f' || k.Key == 'c') {
+			if k.Key == 'f' {
+				statement_formatted = !statement_formatted
+				DisplayStatement(c, *statement, statement_formatted)
+				return
+			}
+			clipboard.CopyStatement(statement.Text)
+			error_msg.Reset()
+			error_msg.Write(fmt.Sprintf("%d bytes copied to the clipboard", len(statement.Text)),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
+			c.Update("bottom_container", container.PlaceWidget(error_msg))
+		} else if k.Key == 'k' && current_mode == "processlist" && show_processlist && !waiting_input {
+			if row, ok := view.Selected(); ok {
+				killThread(row)
+			} else {
+				selectThread("kill")
+			}
+		} else if k.Key == 'K' && current_mode == "processlist" && show_processlist {
+			// all the threads matching the filter, but innotopgo
+			if len(view.Filter.String()) == 0 {
+				error_msg.Reset()
+				error_msg.Write("set a filter with <F> to kill all the threads matching it",
+					text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+				c.Update("bottom_container", container.PlaceWidget(error_msg))
+				return
+			}
+			dialog := &KillDialog{}
+			for _, row := range view.Shown() {
+				if view.Hide.Own(row) {
+					dialog.Skipped++
+					continue
+				}
+				dialog.Rows = append(dialog.Rows, row)
+			}
+			openKill(dialog)
+		} else if current_mode == "kill_dialog" && (k.Key == 'q' || k.Key == 'c' || k.Key == 'y') {
+			if kill_dialog.Done() {
+				return
+			}
+			switch k.Key {
+			case 'q':
+				kill_dialog.Connection = false
+			case 'c':
+				kill_dialog.Connection = true
+			case 'y':
+				kill_dialog.Run(servers.DB())
 			}
-		} else if (k.Key == 'k' || k.Key == 'K') && show_processlist {
-			waiting_input = true
-			c.Update("bottom_container", container.PlaceWidget(bottom_input))
-			c.Update("bottom_container", container.Focused())
-			current_mode = "kill"
+			c.Update("main_container", container.BorderTitle(kill_dialog.Title()))
+			kill_dialog.Display(main_window)
 		} else if k.Key == keyboard.KeyBackspace2 && !waiting_input {
-			if !show_processlist {
+			if explain_sample != nil && strings.HasPrefix(current_mode, "explain_") {
+				// back to the digest of the sample query
+				explain_sample = nil
+				openTopQueries()
+			} else if current_mode == "statement" && statement_from == "thread_details" {
+				// back to the details of the thread
+				c.Update("bottom_container", container.Clear())
+				current_mode = "thread_details"
+				openThread(thread_id)
+			} else if !show_processlist {
 				show_processlist = true
-				BackToMainView(c, top_window, main_window, tlg, trg, current_mode)
+				BackToMainView(c, top_window, list_window, tlg, trg, current_mode)
 				current_mode = "processlist"
 				thread_id = "0"
+			} else if current_mode == "processlist" && view.Group.Back(&view.Filter) {
+				// back to the groups from the threads of one of them
+				c.Update("main_container", container.BorderTitle(processlistTitle(view)))
+				redraw()
 			}
+		} else if (k.Key == 'a' || k.Key == 'A') && explain_sample != nil && strings.HasPrefix(current_mode, "explain_") &&
+			!strings.HasPrefix(strings.ToUpper(strings.TrimSpace(explain_sample.Text)), "SELECT") {
+			// EXPLAIN ANALYZE runs the statement, the sample query was run
+			// by a client and must not be run again
+			error_msg.Reset()
+			error_msg.Write("EXPLAIN ANALYZE only runs the sample queries which are a SELECT",
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+			c.Update("bottom_container", container.PlaceWidget(error_msg))
 		} else if k.Key == 'a' {
 			if strings.HasPrefix(current_mode, "explain_") && current_mode != "explain_analyze" {
 				main_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "ANALYZE")
+				err := explain("ANALYZE")
 				if err != nil {
 					main_window.Write("Aborting... the query was too long, use <A> to ignore timeout.",
 						text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
@@ -599,7 +1223,7 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		} else if k.Key == 'A' {
 			if strings.HasPrefix(current_mode, "explain_") && current_mode != "explain_analyze" {
 				main_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "ANALYZE /*NO_TIMEOUT*/ ")
+				err := explain("ANALYZE /*NO_TIMEOUT*/ ")
 				if err != nil {
 					cancel()
 					t.Close()
@@ -611,7 +1235,7 @@ func DisplayProcesslist(mydb *sql.DB) error {
 		} else if k.Key == keyboard.KeySpace {
 			if current_mode == "explain_normal" {
 				main_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "FORMAT=TREE")
+				err := explain("FORMAT=TREE")
 				if err != nil {
 					cancel()
 					t.Close()
@@ -620,7 +1244,7 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				current_mode = "explain_tree"
 			} else if current_mode == "explain_tree" {
 				main_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "FORMAT=JSON")
+				err := explain("FORMAT=JSON")
 				if err != nil {
 					cancel()
 					t.Close()
@@ -629,7 +1253,7 @@ func DisplayProcesslist(mydb *sql.DB) error {
 				current_mode = "explain_json"
 			} else if current_mode == "explain_json" {
 				main_window.Reset()
-				err := DisplayExplain(ctx, mydb, c, top_window, main_window, thread_id, "NORMAL")
+				err := explain("NORMAL")
 				if err != nil {
 					cancel()
 					t.Close()
@@ -639,7 +1263,8 @@ func DisplayProcesslist(mydb *sql.DB) error {
 			} else if show_processlist {
 				if !processlist_drawing {
 					processlist_drawing = true
-					err = DisplayProcesslistContent(mydb, main_window)
+					srv := servers.Current()
+					err = DisplayProcesslistContent(srv.DB, srv.Capabilities, list_window, footer, view)
 					if err != nil {
 						cancel()
 						t.Close()
@@ -648,11 +1273,44 @@ func DisplayProcesslist(mydb *sql.DB) error {
 					processlist_drawing = false
 				}
 			}
+		} else if k.Key == 'f' || k.Key == 'F' {
+			if current_mode == "processlist" && !waiting_input {
+				waiting_input = true
+				c.Update("bottom_container", container.PlaceWidget(filter_input))
+				c.Update("bottom_container", container.Focused())
+				current_mode = "filter"
+			}
+		} else if (k.Key == keyboard.KeyArrowUp || k.Key == keyboard.KeyArrowDown) && current_mode == "processlist" && !waiting_input {
+			if k.Key == keyboard.KeyArrowUp {
+				view.MoveCursor(-1)
+			} else {
+				view.MoveCursor(1)
+			}
+			redraw()
+		} else if (k.Key == keyboard.KeyPgUp || k.Key == keyboard.KeyPgDn) && current_mode == "processlist" && !waiting_input {
+			if k.Key == keyboard.KeyPgUp {
+				view.MovePage(-1)
+			} else {
+				view.MovePage(1)
+			}
+			redraw()
+		} else if (k.Key == '<' || k.Key == '>') && current_mode == "processlist" && !waiting_input {
+			if k.Key == '<' {
+				view.Sort.Move(-1)
+			} else {
+				view.Sort.Move(1)
+			}
+			redraw()
 		}
 
 	}
 
-	if err := termdash.Run(ctx, t, c, termdash.KeyboardSubscriber(quitter), termdash.RedrawInterval(redrawInterval)); err != nil {
+	if key, ok := screen_keys[opts.Screen]; ok {
+		// start on the requested screen as if its key was pressed
+		go quitter(&terminalapi.Keyboard{Key: keyboard.Key(key)})
+	}
+
+	if err := termdash.Run(ctx, clipboard, c, termdash.KeyboardSubscriber(quitter), termdash.RedrawInterval(redrawInterval)); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,149 @@
+package innotop
+
+import (
+	"github.com/lefred/innotopgo/db"
+)
+
+// rowKey identifies the thread of the row, or the group of the grouped
+// processlist
+func rowKey(row db.Row) string {
+	if group := row.Get("group_key"); !group.IsNull() {
+		return "group " + group.String()
+	}
+	return row.Get("conn_id").String()
+}
+
+// place records the rows displayed, the threads they show, and returns the
+// first row to display and the position of the selected one. The selection
+// stays on the same thread, or on the same line when the thread is gone.
+func (view *ProcesslistView) place(rows []db.Row, threads []db.Row, page int) (int, int) {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	view.rows = rows
+	view.threads = threads
+	view.page = page
+	for i, row := range rows {
+		if rowKey(row) == view.selected {
+			view.cursor = i
+			break
+		}
+	}
+	view.clamp()
+	return view.offset, view.cursor
+}
+
+// clamp keeps the cursor on a row and the page around the cursor, the
+// caller holds the mutex
+func (view *ProcesslistView) clamp() {
+	if view.cursor >= len(view.rows) {
+		view.cursor = len(view.rows) - 1
+	}
+	if view.cursor < 0 {
+		view.cursor = 0
+	}
+	view.selected = ""
+	if len(view.rows) > 0 {
+		view.selected = rowKey(view.rows[view.cursor])
+	}
+	if view.cursor < view.offset {
+		view.offset = view.cursor
+	}
+	if view.page > 0 && view.cursor >= view.offset+view.page {
+		view.offset = view.cursor - view.page + 1
+	}
+	if view.page > 0 && view.offset > len(view.rows)-view.page {
+		view.offset = len(view.rows) - view.page
+	}
+	if view.offset < 0 {
+		view.offset = 0
+	}
+}
+
+// MoveCursor moves the selection by lines, a page being the rows displayed
+func (view *ProcesslistView) MoveCursor(lines int) {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	view.cursor += lines
+	view.clamp()
+}
+
+// MovePage moves the selection by pages
+func (view *ProcesslistView) MovePage(pages int) {
+	view.mutex.Lock()
+	page := view.page
+	view.mutex.Unlock()
+	if page < 1 {
+		page = 1
+	}
+	view.MoveCursor(pages * page)
+}
+
+// Click selects the row displayed on the line of the window, the first line
+// being the header
+func (view *ProcesslistView) Click(line int) {
+	if line < 1 {
+		return
+	}
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	if view.offset+line-1 >= len(view.rows) {
+		return
+	}
+	view.cursor = view.offset + line - 1
+	view.clamp()
+}
+
+// Selected returns the row of the selected thread, false when the
+// processlist is empty or grouped
+func (view *ProcesslistView) Selected() (db.Row, bool) {
+	row, ok := view.selectedRow()
+	if !ok || !row.Get("group_key").IsNull() {
+		return db.Row{}, false
+	}
+	return row, true
+}
+
+// Thread returns the row of the thread of the last processlist read, the
+// filter and the hidden threads aside. The id is the thread id, or the
+// connection id when information_schema gives no thread id.
+func (view *ProcesslistView) Thread(id string) (db.Row, bool) {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	if view.last == nil {
+		return db.Row{}, false
+	}
+	for _, row := range view.last.Rows {
+		thd_id := row.Get("thd_id")
+		if (!thd_id.IsNull() && thd_id.String() == id) || (thd_id.IsNull() && row.Get("conn_id").String() == id) {
+			return row, true
+		}
+	}
+	return db.Row{}, false
+}
+
+// SelectedGroup returns the row of the selected group, false when the
+// processlist is not grouped
+func (view *ProcesslistView) SelectedGroup() (db.Row, bool) {
+	row, ok := view.selectedRow()
+	if !ok || row.Get("group_key").IsNull() {
+		return db.Row{}, false
+	}
+	return row, true
+}
+
+func (view *ProcesslistView) selectedRow() (db.Row, bool) {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	if view.cursor >= len(view.rows) {
+		return db.Row{}, false
+	}
+	return view.rows[view.cursor], true
+}
+
+// Shown returns the threads displayed, after the filter and the sort, the
+// ones of all the groups when the processlist is grouped
+func (view *ProcesslistView) Shown() []db.Row {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	return append([]db.Row{}, view.threads...)
+}
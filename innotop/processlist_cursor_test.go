@@ -0,0 +1,165 @@
+package innotop
+
+import (
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// cursorThreads returns the rows of the threads with the connection ids,
+// sorted by the server in this order
+func cursorThreads(ids ...string) [][]interface{} {
+	var rows [][]interface{}
+	for i, id := range ids {
+		rows = append(rows, []interface{}{"Query", id, "app@10.0.0.5", (len(ids) - i) * 1000000000000, "foreground"})
+	}
+	return rows
+}
+
+// newCursorView returns a processlist view drawn in a window of height
+// lines, 0 before the first draw, and the function refreshing it with the
+// threads of the connection ids
+func newCursorView(t *testing.T, height int) (*ProcesslistView, func(ids ...string)) {
+	caps := newCapabilities("8.0.36", true, true)
+	stmt := processlistStatement(caps)
+	columns := []string{"command", "conn_id", "user", "sort_time", "thread_type"}
+	fake := db.NewFake()
+	main_text, err := text.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer, err := text.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	window := NewProcesslistWindow(main_text)
+	window.height = height
+	view := &ProcesslistView{}
+	return view, func(ids ...string) {
+		t.Helper()
+		fake.Set(stmt, columns, cursorThreads(ids...)...)
+		if err := DisplayProcesslistContent(fake, caps, window, footer, view); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcesslistCursor(t *testing.T) {
+	view, refresh := newCursorView(t, 0)
+	check := func(want string) {
+		t.Helper()
+		row, ok := view.Selected()
+		if len(want) == 0 {
+			if ok {
+				t.Errorf("thread %s is selected, want none", row.Get("conn_id").String())
+			}
+			return
+		}
+		if !ok || row.Get("conn_id").String() != want {
+			t.Errorf("thread %s is selected, want %s", row.Get("conn_id").String(), want)
+		}
+	}
+
+	refresh("10", "11", "12", "13")
+	check("10")
+	view.MoveCursor(2)
+	check("12")
+
+	// the selection follows the thread when the list changes around it
+	refresh("9", "10", "11", "12", "13")
+	check("12")
+	refresh("12", "13")
+	check("12")
+	refresh("14", "15", "13", "12")
+	check("12")
+
+	// the thread is gone, the selection stays on the same line
+	refresh("14", "15", "13", "16")
+	check("16")
+	view.MoveCursor(-1)
+	check("13")
+	refresh("14", "15", "17")
+	check("17")
+
+	// the list shrinks below the cursor, the last thread is selected
+	refresh("14")
+	check("14")
+	view.MoveCursor(5)
+	check("14")
+	view.MoveCursor(-5)
+	check("14")
+	refresh()
+	check("")
+	refresh("20", "21")
+	check("20")
+}
+
+func TestProcesslistCursorPage(t *testing.T) {
+	// the header and 3 threads fit in the window
+	view, refresh := newCursorView(t, 4)
+	position := func(offset int, selected string) {
+		t.Helper()
+		row, ok := view.Selected()
+		if !ok || row.Get("conn_id").String() != selected || view.offset != offset {
+			t.Errorf("thread %s is selected from offset %d, want %s from %d", row.Get("conn_id").String(), view.offset, selected, offset)
+		}
+	}
+
+	refresh("1", "2", "3", "4", "5", "6", "7")
+	position(0, "1")
+	view.MovePage(1)
+	position(1, "4")
+	view.MoveCursor(3)
+	position(4, "7")
+	view.MovePage(1)
+	position(4, "7")
+
+	// the third line of the window shows the thread 6
+	view.Click(2)
+	position(4, "6")
+	view.Click(9)
+	position(4, "6")
+
+	// the page is moved back when the list shrinks
+	refresh("1", "2", "5", "6")
+	position(1, "6")
+	refresh("1", "2")
+	position(0, "2")
+	view.MovePage(-1)
+	position(0, "1")
+}
+
+func TestProcesslistThread(t *testing.T) {
+	var view ProcesslistView
+	if _, ok := view.Thread("48"); ok {
+		t.Error("thread found before the processlist is read")
+	}
+	view.last = db.NewResult([]string{"thd_id", "conn_id", "command"}, [][]interface{}{
+		{"48", "9", "Query"},
+		{"49", "10", "Sleep"},
+		// information_schema has no thread id
+		{nil, "12", "Query"},
+	})
+	// the filter does not hide the threads from the lookup
+	if err := view.Filter.Set("cmd!=Sleep"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id      string
+		conn_id string
+	}{
+		{id: "48", conn_id: "9"},
+		{id: "49", conn_id: "10"},
+		{id: "12", conn_id: "12"},
+		// a connection id with a thread id is not a thread id
+		{id: "9"},
+		{id: "99"},
+	}
+	for _, test := range tests {
+		row, ok := view.Thread(test.id)
+		if ok != (len(test.conn_id) > 0) || row.Get("conn_id").String() != test.conn_id {
+			t.Errorf("thread %s is connection %q, want %q", test.id, row.Get("conn_id").String(), test.conn_id)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package innotop
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// filter_fields are the fields a filter term can test, with the column of
+// GetProcesslist they are read from
+var filter_fields = map[string]string{
+	"user":  "user",
+	"host":  "user",
+	"db":    "db",
+	"cmd":   "command",
+	"state": "state",
+	"stmt":  "full_statement",
+	"time":  "sort_time",
+	"lock":  "lock_time",
+	"rows":  "rows_examined",
+	// the digest of the statement, see statementDigest
+	"digest": "digest",
+}
+
+// duration_fields are the fields holding picoseconds, their values are
+// given as durations like 5s or 200ms
+var duration_fields = map[string]bool{
+	"time": true,
+	"lock": true,
+}
+
+var filter_operators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// filterTerm is one test of a filter, like cmd!=Sleep or time>5s
+type filterTerm struct {
+	field    string
+	operator string
+	value    string
+	number   float64
+	regexp   *regexp.Regexp
+}
+
+// parseFilter parses a filter made of terms separated by spaces, all of them
+// having to match. A term is a field, an operator and a value:
+//
+//	user=app db=orders cmd!=Sleep stmt~/SELECT.*FOR UPDATE/ time>5s
+//
+// user, host, db, cmd, state, stmt and digest are compared as text with =
+// and !=, or matched with the case insensitive regular expressions given to
+// ~ and !~. time and lock take durations, rows a number, with =, !=, >, >=,
+// < and <=.
+func parseFilter(expr string) ([]filterTerm, error) {
+	var terms []filterTerm
+	rest := strings.TrimSpace(expr)
+	for len(rest) > 0 {
+		i := strings.IndexAny(rest, "!=~<>")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid filter term '%s', use <field><operator><value>", strings.Fields(rest)[0])
+		}
+		term := filterTerm{field: strings.ToLower(rest[:i])}
+		if _, ok := filter_fields[term.field]; !ok {
+			return nil, fmt.Errorf("unknown filter field '%s', use one of: %s", rest[:i], filterFieldNames())
+		}
+		rest = rest[i:]
+		for _, operator := range filter_operators {
+			if strings.HasPrefix(rest, operator) {
+				term.operator = operator
+				break
+			}
+		}
+		if len(term.operator) == 0 {
+			return nil, fmt.Errorf("invalid operator in filter term '%s'", term.field+rest)
+		}
+		rest = rest[len(term.operator):]
+		term.value, rest = filterValue(rest)
+		if err := term.compile(); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		rest = strings.TrimSpace(rest)
+	}
+	return terms, nil
+}
+
+// filterValue splits the value of a term from the rest of the filter. A
+// value between slashes or quotes can contain spaces.
+func filterValue(s string) (string, string) {
+	if len(s) > 1 && (s[0] == '/' || s[0] == '"' || s[0] == '\'') {
+		if end := strings.IndexByte(s[1:], s[0]); end >= 0 {
+			return s[1 : end+1], s[end+2:]
+		}
+	}
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+func (term *filterTerm) compile() error {
+	numeric := term.field == "rows" || duration_fields[term.field]
+	switch term.operator {
+	case "~", "!~":
+		if numeric {
+			return fmt.Errorf("%s cannot be matched with %s, compare it with =, !=, >, >=, < or <=", term.field, term.operator)
+		}
+		re, err := regexp.Compile("(?i)" + term.value)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression '%s': %v", term.value, err)
+		}
+		term.regexp = re
+		return nil
+	case "=", "!=":
+		if !numeric {
+			return nil
+		}
+	default:
+		if !numeric {
+			return fmt.Errorf("%s cannot be compared with %s, use =, !=, ~ or !~", term.field, term.operator)
+		}
+	}
+	if duration_fields[term.field] {
+		d, err := time.ParseDuration(term.value)
+		if err != nil {
+			// a number of seconds
+			seconds, err := strconv.ParseFloat(term.value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid duration '%s' for %s, use for example 5s or 200ms", term.value, term.field)
+			}
+			d = time.Duration(seconds * float64(time.Second))
+		}
+		// the latencies of performance_schema are in picoseconds
+		term.number = float64(d.Nanoseconds()) * 1000
+		return nil
+	}
+	number, err := strconv.ParseFloat(term.value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number '%s' for %s", term.value, term.field)
+	}
+	term.number = number
+	return nil
+}
+
+// match tests the term on a row of the processlist. A NULL value only
+// matches an empty value given to =, like db= for the threads without a
+// default database.
+func (term *filterTerm) match(row db.Row) bool {
+	if term.field == "digest" {
+		return term.matchText(statementDigest(row))
+	}
+	value := row.Get(filter_fields[term.field])
+	if term.field == "stmt" {
+		value = fullStatement(row)
+	}
+	if value.IsNull() {
+		return term.operator == "=" && len(term.value) == 0
+	}
+	if term.field == "rows" || duration_fields[term.field] {
+		number, ok := value.Float64()
+		if !ok {
+			return false
+		}
+		switch term.operator {
+		case "=":
+			return number == term.number
+		case "!=":
+			return number != term.number
+		case ">":
+			return number > term.number
+		case ">=":
+			return number >= term.number
+		case "<":
+			return number < term.number
+		default:
+			return number <= term.number
+		}
+	}
+	text := value.String()
+	switch term.field {
+	case "user":
+		text, _ = splitAccount(text)
+	case "host":
+		_, text = splitAccount(text)
+	}
+	return term.matchText(text)
+}
+
+func (term *filterTerm) matchText(text string) bool {
+	switch term.operator {
+	case "=":
+		return strings.EqualFold(text, term.value)
+	case "!=":
+		return !strings.EqualFold(text, term.value)
+	case "~":
+		return term.regexp.MatchString(text)
+	default:
+		return !term.regexp.MatchString(text)
+	}
+}
+
+// fullStatement returns the statement of the thread as the server has it,
+// current_statement being shortened by sys.format_statement. The rows read
+// without it, like the ones of the recordings made before, give
+// current_statement.
+func fullStatement(row db.Row) db.Value {
+	if stmt := row.Get("full_statement"); !stmt.IsNull() {
+		return stmt
+	}
+	return row.Get("current_statement")
+}
+
+// splitAccount returns the user and the host, without the port, of the
+// user column of the processlist. The background threads have no host.
+func splitAccount(account string) (string, string) {
+	i := strings.LastIndex(account, "@")
+	if i < 0 {
+		return account, ""
+	}
+	host := account[i+1:]
+	// information_schema gives host:port, an IPv6 address has several ':'
+	if strings.Count(host, ":") == 1 {
+		host = host[:strings.Index(host, ":")]
+	}
+	return account[:i], host
+}
+
+func filterFieldNames() string {
+	var names []string
+	for name := range filter_fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ProcesslistFilter is the filter of the processlist chosen by the user, it
+// is kept across the refreshes and the server switches.
+type ProcesslistFilter struct {
+	mutex sync.Mutex
+	// Named are the filters saved in the configuration file
+	Named map[string]string
+	text  string
+	terms []filterTerm
+}
+
+// Set replaces the filter by an expression or by the name of a saved
+// filter, an empty input removes the filter.
+func (filter *ProcesslistFilter) Set(input string) error {
+	input = strings.TrimSpace(input)
+	// the names of the configuration file use '-' as word separator
+	if expr, ok := filter.Named[strings.ReplaceAll(strings.ToLower(input), "_", "-")]; ok {
+		input = expr
+	}
+	terms, err := parseFilter(input)
+	if err != nil {
+		return err
+	}
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	filter.text = input
+	filter.terms = terms
+	return nil
+}
+
+// String returns the active filter, empty when the processlist is not
+// filtered
+func (filter *ProcesslistFilter) String() string {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	return filter.text
+}
+
+// Apply removes the rows not matching the filter and returns how many
+// were removed
+func (filter *ProcesslistFilter) Apply(result *db.Result) int {
+	filter.mutex.Lock()
+	terms := filter.terms
+	filter.mutex.Unlock()
+	if len(terms) == 0 {
+		return 0
+	}
+	rows := result.Rows[:0]
+	for _, row := range result.Rows {
+		matched := true
+		for i := range terms {
+			if !terms[i].match(row) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			rows = append(rows, row)
+		}
+	}
+	removed := len(result.Rows) - len(rows)
+	result.Rows = rows
+	return removed
+}
@@ -0,0 +1,224 @@
+package innotop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr  string
+		terms []filterTerm
+		err   string
+	}{
+		{expr: ""},
+		{expr: "user=app", terms: []filterTerm{{field: "user", operator: "=", value: "app"}}},
+		{expr: "  cmd!=Sleep   DB=  ", terms: []filterTerm{
+			{field: "cmd", operator: "!=", value: "Sleep"},
+			{field: "db", operator: "="},
+		}},
+		{expr: `stmt~/FOR UPDATE/ state!~'sending data'`, terms: []filterTerm{
+			{field: "stmt", operator: "~", value: "FOR UPDATE"},
+			{field: "state", operator: "!~", value: "sending data"},
+		}},
+		{expr: "time>5s lock>=200ms rows<=1000", terms: []filterTerm{
+			{field: "time", operator: ">", value: "5s", number: 5e12},
+			{field: "lock", operator: ">=", value: "200ms", number: 2e11},
+			{field: "rows", operator: "<=", value: "1000", number: 1000},
+		}},
+		{expr: "time<1.5 time=1m", terms: []filterTerm{
+			{field: "time", operator: "<", value: "1.5", number: 1.5e12},
+			{field: "time", operator: "=", value: "1m", number: 6e13},
+		}},
+		{expr: "app", err: "invalid filter term 'app'"},
+		{expr: "=app", err: "invalid filter term '=app'"},
+		{expr: "name=app", err: "unknown filter field 'name'"},
+		{expr: "user>app", err: "user cannot be compared with >"},
+		{expr: "time~5s", err: "time cannot be matched with ~"},
+		{expr: "time>soon", err: "invalid duration 'soon'"},
+		{expr: "rows>many", err: "invalid number 'many'"},
+		{expr: "stmt~/(/", err: "invalid regular expression '('"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			terms, err := parseFilter(test.expr)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error is %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(terms) != len(test.terms) {
+				t.Fatalf("got %d terms, want %d", len(terms), len(test.terms))
+			}
+			for i, term := range terms {
+				want := test.terms[i]
+				if term.field != want.field || term.operator != want.operator || term.value != want.value || term.number != want.number {
+					t.Errorf("term %d is %s %s %q %v, want %s %s %q %v", i, term.field, term.operator, term.value, term.number,
+						want.field, want.operator, want.value, want.number)
+				}
+				if (term.regexp != nil) != (want.operator == "~" || want.operator == "!~") {
+					t.Errorf("term %d has regexp %v", i, term.regexp)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterValue(t *testing.T) {
+	tests := []struct {
+		s     string
+		value string
+		rest  string
+	}{
+		{s: "app", value: "app"},
+		{s: "app db=shop", value: "app", rest: " db=shop"},
+		{s: "app\tdb=shop", value: "app", rest: "\tdb=shop"},
+		{s: "/FOR UPDATE/ time>5s", value: "FOR UPDATE", rest: " time>5s"},
+		{s: `"sending data"`, value: "sending data"},
+		{s: `'it"s'`, value: `it"s`},
+		{s: `/a\/b/`, value: `a\`, rest: "b/"},
+		// an unterminated quote is part of the value
+		{s: `/unterminated x`, value: "/unterminated", rest: " x"},
+		{s: ""},
+	}
+	for _, test := range tests {
+		value, rest := filterValue(test.s)
+		if value != test.value || rest != test.rest {
+			t.Errorf("filterValue(%q) = %q, %q, want %q, %q", test.s, value, rest, test.value, test.rest)
+		}
+	}
+}
+
+func TestSplitAccount(t *testing.T) {
+	tests := []struct {
+		account string
+		user    string
+		host    string
+	}{
+		{account: "app@10.0.0.5", user: "app", host: "10.0.0.5"},
+		{account: "app@10.0.0.5:51234", user: "app", host: "10.0.0.5"},
+		{account: "app@db1.example.com:3306", user: "app", host: "db1.example.com"},
+		{account: "app@::1", user: "app", host: "::1"},
+		{account: "app@fe80::1:2", user: "app", host: "fe80::1:2"},
+		{account: "us@er@localhost", user: "us@er", host: "localhost"},
+		{account: "sql/event_scheduler", user: "sql/event_scheduler"},
+		{account: "app@", user: "app"},
+	}
+	for _, test := range tests {
+		if user, host := splitAccount(test.account); user != test.user || host != test.host {
+			t.Errorf("splitAccount(%q) = %q, %q, want %q, %q", test.account, user, host, test.user, test.host)
+		}
+	}
+}
+
+// filter_rows are the threads matched by the filters, their conn_id is
+// their position
+var filter_rows = [][]interface{}{
+	{"1", "Query", "app@10.0.0.5:51234", "shop", "executing", "SELECT * FROM orders FOR UPDATE", "6000000000000", "1000000", "1200"},
+	{"2", "Sleep", "app@10.0.0.6", nil, nil, nil, "0", nil, nil},
+	{"3", "Query", "report@10.0.0.9", "reporting", "Sending data", "select sum(amount) from sales", "120000000000000", "2000000", "90000"},
+	{"4", "Query", "sql/event_scheduler", nil, "Waiting on empty queue", nil, "200000000000", nil, "0"},
+	{"5", "Query", "app@10.0.0.5", "shop", "update", "UPDATE stock SET qty=qty-1", "4000000000000", "300000000000", "1"},
+}
+
+var filter_columns = []string{"conn_id", "command", "user", "db", "state", "current_statement", "sort_time", "lock_time", "rows_examined"}
+
+func TestFilterApply(t *testing.T) {
+	tests := []struct {
+		expr string
+		// want are the conn_id of the threads matching
+		want string
+	}{
+		{expr: "", want: "1 2 3 4 5"},
+		{expr: "user=app", want: "1 2 5"},
+		{expr: "user=APP", want: "1 2 5"},
+		{expr: "user!=app", want: "3 4"},
+		{expr: "host=10.0.0.5", want: "1 5"},
+		{expr: "host=", want: "4"},
+		{expr: "db=", want: "2 4"},
+		{expr: "db!=shop", want: "3"},
+		{expr: "cmd!=Sleep", want: "1 3 4 5"},
+		{expr: "cmd=sleep", want: "2"},
+		{expr: "state~data", want: "3"},
+		{expr: "state!~^waiting", want: "1 3 5"},
+		{expr: "stmt~/for update/", want: "1"},
+		{expr: `stmt~/^\w+\s+sum\(/`, want: "3"},
+		{expr: `stmt~/\bSELECT\s/`, want: "1 3"},
+		{expr: "stmt!~/^select/", want: "5"},
+		{expr: "time>5s", want: "1 3"},
+		{expr: "time>=4s", want: "1 3 5"},
+		{expr: "time<1s", want: "2 4"},
+		{expr: "time<=200ms", want: "2 4"},
+		{expr: "time=2m", want: "3"},
+		{expr: "time>90", want: "3"},
+		{expr: "lock>200ms", want: "5"},
+		{expr: "lock!=1us", want: "3 5"},
+		{expr: "rows>1000", want: "1 3"},
+		{expr: "rows=0", want: "4"},
+		{expr: "rows<2", want: "4 5"},
+		{expr: "user=app cmd!=Sleep time>5s", want: "1"},
+		{expr: "user=nobody"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			var filter ProcesslistFilter
+			if err := filter.Set(test.expr); err != nil {
+				t.Fatal(err)
+			}
+			result := db.NewResult(filter_columns, filter_rows)
+			removed := filter.Apply(result)
+			var ids []string
+			for _, row := range result.Rows {
+				ids = append(ids, row.Get("conn_id").String())
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("threads matching are %q, want %q", got, test.want)
+			}
+			if removed != len(filter_rows)-len(ids) {
+				t.Errorf("%d threads removed, want %d", removed, len(filter_rows)-len(ids))
+			}
+			if filter.String() != strings.TrimSpace(test.expr) {
+				t.Errorf("filter is %q, want %q", filter.String(), test.expr)
+			}
+		})
+	}
+}
+
+func TestFilterNamed(t *testing.T) {
+	// the filters of the configuration file are given as written
+	filter := ProcesslistFilter{Named: map[string]string{
+		"selects":  `cmd!=Sleep stmt~/\bSELECT\s/`,
+		"slow-app": "user=app time>5s",
+	}}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "selects", want: "1 3"},
+		{input: " slow_app ", want: "1"},
+		{input: "Slow-App", want: "1"},
+	}
+	for _, test := range tests {
+		if err := filter.Set(test.input); err != nil {
+			t.Fatal(err)
+		}
+		result := db.NewResult(filter_columns, filter_rows)
+		filter.Apply(result)
+		var ids []string
+		for _, row := range result.Rows {
+			ids = append(ids, row.Get("conn_id").String())
+		}
+		if got := strings.Join(ids, " "); got != test.want {
+			t.Errorf("threads matching %q are %q, want %q", test.input, got, test.want)
+		}
+	}
+	if err := filter.Set("unknown"); err == nil {
+		t.Error("no error for an unknown filter name")
+	}
+}
@@ -0,0 +1,242 @@
+package innotop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// group_fields are the filter fields the processlist can be grouped on, in
+// the order of <g>, with the title of their column
+var group_fields = []struct {
+	field  string
+	header string
+}{
+	{field: "user", header: "User"},
+	{field: "host", header: "Host"},
+	{field: "db", header: "Db"},
+	{field: "cmd", header: "Command"},
+	{field: "state", header: "State"},
+	{field: "digest", header: "Statement"},
+}
+
+// group_format is the layout of the lines of the grouped processlist
+const group_format = "%8v %8v %12v %12v %14v  %-v\n"
+
+// the literals replaced by ? to tell the statements apart without a digest
+var (
+	statement_literals = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"|\b0x[0-9a-fA-F]+\b|\b\d+(?:\.\d+)?\b`)
+	statement_lists    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	statement_spaces   = regexp.MustCompile(`\s+`)
+)
+
+// normalizeStatement replaces the literals of the statement by ? and the
+// lists of literals by (...)
+func normalizeStatement(stmt string) string {
+	stmt = statement_literals.ReplaceAllString(stmt, "?")
+	stmt = statement_lists.ReplaceAllString(stmt, "(...)")
+	return strings.TrimSpace(statement_spaces.ReplaceAllString(stmt, " "))
+}
+
+// statementDigest returns the digest of the statement running on the thread,
+// empty when it runs none. performance_schema gives it, otherwise it is
+// computed from the normalized statement.
+func statementDigest(row db.Row) string {
+	stmt := row.Get("current_statement").String()
+	if len(stmt) == 0 {
+		return ""
+	}
+	if digest := row.Get("digest").String(); len(digest) > 0 {
+		return digest
+	}
+	sum := sha256.Sum256([]byte(normalizeStatement(stmt)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// groupKey returns the value of the field the row is grouped on
+func groupKey(field string, row db.Row) string {
+	switch field {
+	case "user":
+		user, _ := splitAccount(row.Get("user").String())
+		return user
+	case "host":
+		_, host := splitAccount(row.Get("user").String())
+		return host
+	case "digest":
+		return statementDigest(row)
+	}
+	return row.Get(filter_fields[field]).String()
+}
+
+// filterEquals returns the filter term matching the threads whose field has
+// the value. The value is quoted when it contains spaces or quotes, and
+// matched by a regular expression when it contains all the quotes, its
+// slashes being written \x2f.
+func filterEquals(field string, value string) string {
+	if !strings.ContainsAny(value, " \t\"'/") {
+		return field + "=" + value
+	}
+	for _, quote := range []string{`"`, `'`, `/`} {
+		if !strings.Contains(value, quote) {
+			return field + "=" + quote + value + quote
+		}
+	}
+	return field + "~/^" + strings.ReplaceAll(regexp.QuoteMeta(value), "/", `\x2f`) + "$/"
+}
+
+// ProcesslistGroup is the field the processlist is grouped on, it is kept
+// across the refreshes and the server switches.
+type ProcesslistGroup struct {
+	mutex sync.Mutex
+	// current is the position in group_fields plus one, 0 when the
+	// processlist is not grouped
+	current int
+	// back is the grouping and the filter restored when leaving a group
+	back *groupBack
+}
+
+type groupBack struct {
+	current int
+	filter  string
+	// opened is the filter showing the group, a filter set since then is
+	// kept
+	opened string
+}
+
+// Next groups the processlist on the next field, the last one being
+// followed by the threads
+func (group *ProcesslistGroup) Next() {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	group.current = (group.current + 1) % (len(group_fields) + 1)
+	group.back = nil
+}
+
+// Field returns the filter field the processlist is grouped on, empty when
+// it is not grouped
+func (group *ProcesslistGroup) Field() string {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	if group.current == 0 {
+		return ""
+	}
+	return group_fields[group.current-1].field
+}
+
+// Open shows the threads of the group of the row by adding its value to the
+// filter, Back returns to the groups
+func (group *ProcesslistGroup) Open(row db.Row, filter *ProcesslistFilter) error {
+	field := group.Field()
+	if len(field) == 0 {
+		return nil
+	}
+	previous := filter.String()
+	term := filterEquals(field, row.Get("group_key").String())
+	if err := filter.Set(strings.TrimSpace(previous + " " + term)); err != nil {
+		return err
+	}
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	group.back = &groupBack{current: group.current, filter: previous, opened: filter.String()}
+	group.current = 0
+	return nil
+}
+
+// Back returns to the groups left by Open and restores the filter, unless
+// another filter was set. It returns false when no group was opened.
+func (group *ProcesslistGroup) Back(filter *ProcesslistFilter) bool {
+	group.mutex.Lock()
+	back := group.back
+	group.back = nil
+	if back != nil {
+		group.current = back.current
+	}
+	group.mutex.Unlock()
+	if back == nil {
+		return false
+	}
+	if filter.String() == back.opened {
+		filter.Set(back.filter)
+	}
+	return true
+}
+
+// groupProcesslist replaces the threads by their groups on the field, the
+// groups with the most threads first. The latencies and the rows examined
+// are the ones of the statements running.
+func groupProcesslist(field string, result *db.Result) *db.Result {
+	type aggregate struct {
+		key, label           string
+		threads, active      int64
+		total, longest, rows float64
+	}
+	groups := map[string]*aggregate{}
+	var keys []string
+	for _, row := range result.Rows {
+		key := groupKey(field, row)
+		agg, ok := groups[key]
+		if !ok {
+			agg = &aggregate{key: key, label: key}
+			if field == "digest" && len(key) > 0 {
+				agg.label = normalizeStatement(row.Get("current_statement").String())
+			}
+			groups[key] = agg
+			keys = append(keys, key)
+		}
+		agg.threads++
+		latency, _ := row.Get("sort_time").Float64()
+		if latency > 0 {
+			agg.active++
+		}
+		agg.total += latency
+		if latency > agg.longest {
+			agg.longest = latency
+		}
+		rows, _ := row.Get("rows_examined").Float64()
+		agg.rows += rows
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := groups[keys[i]], groups[keys[j]]
+		if a.threads != b.threads {
+			return a.threads > b.threads
+		}
+		return a.total > b.total
+	})
+	var rows [][]interface{}
+	for _, key := range keys {
+		agg := groups[key]
+		rows = append(rows, []interface{}{agg.key, agg.label, agg.threads, agg.active, agg.total, agg.longest, agg.rows})
+	}
+	return db.NewResult([]string{"group_key", "label", "threads", "active", "total_time", "max_time", "rows_examined"}, rows)
+}
+
+// groupHeader returns the header of the processlist grouped on the field
+func groupHeader(field string) string {
+	header := ""
+	for _, group := range group_fields {
+		if group.field == field {
+			header = group.header
+		}
+	}
+	return fmt.Sprintf(group_format, "Threads", "Active", "Total Time", "Max Time", "Rows Examined", header)
+}
+
+// groupLine returns the line of a group, the threads without a value being
+// grouped under (none)
+func groupLine(row db.Row) string {
+	label := row.Get("label").String()
+	if len(label) == 0 {
+		label = "(none)"
+	}
+	total, _ := row.Get("total_time").Float64()
+	longest, _ := row.Get("max_time").Float64()
+	rows, _ := row.Get("rows_examined").Float64()
+	return fmt.Sprintf(group_format, row.Get("threads").String(), row.Get("active").String(),
+		FormatPico(total), FormatPico(longest), fmt.Sprintf("%.0f", rows), label)
+}
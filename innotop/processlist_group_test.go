@@ -0,0 +1,180 @@
+package innotop
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+var group_columns = []string{"conn_id", "command", "user", "db", "state", "current_statement", "sort_time", "rows_examined", "digest"}
+
+// group_rows are the threads grouped, the digest being missing from
+// information_schema
+var group_rows = [][]interface{}{
+	{"1", "Query", "app@10.0.0.5:51234", "shop", "executing", "select * from orders where id = 7", "2000000000000", "10", nil},
+	{"2", "Query", "app@10.0.0.5:51240", "shop", "executing", "select * from orders where id = 8", "5000000000000", "30", nil},
+	{"3", "Sleep", "app@10.0.0.6", "shop", nil, nil, "0", nil, nil},
+	{"4", "Query", "report@10.0.0.5", "reporting", "Sending data", "select sum(amount) from sales where year in (2023, 2024)", "60000000000000", "90000", nil},
+	{"5", "Sleep", "report@10.0.0.9", nil, nil, nil, "0", nil, nil},
+	{"6", "Query", "report@10.0.0.9", "reporting", "Sending data", "select sum(amount) from sales where year in (2022, 2023)", "1000000000000", "500", nil},
+}
+
+func TestGroupProcesslist(t *testing.T) {
+	type group struct {
+		key, label           string
+		threads, active      string
+		total, longest, rows string
+	}
+	tests := []struct {
+		field  string
+		groups []group
+	}{
+		{field: "user", groups: []group{
+			// the same number of threads, the longest total time first
+			{key: "report", label: "report", threads: "3", active: "2", total: "61000000000000", longest: "60000000000000", rows: "90500"},
+			{key: "app", label: "app", threads: "3", active: "2", total: "7000000000000", longest: "5000000000000", rows: "40"},
+		}},
+		{field: "host", groups: []group{
+			// the port is not part of the host
+			{key: "10.0.0.5", label: "10.0.0.5", threads: "3", active: "3", total: "67000000000000", longest: "60000000000000", rows: "90040"},
+			{key: "10.0.0.9", label: "10.0.0.9", threads: "2", active: "1", total: "1000000000000", longest: "1000000000000", rows: "500"},
+			{key: "10.0.0.6", label: "10.0.0.6", threads: "1", active: "0", total: "0", longest: "0", rows: "0"},
+		}},
+		{field: "db", groups: []group{
+			{key: "shop", label: "shop", threads: "3", active: "2", total: "7000000000000", longest: "5000000000000", rows: "40"},
+			{key: "reporting", label: "reporting", threads: "2", active: "2", total: "61000000000000", longest: "60000000000000", rows: "90500"},
+			// the threads without a db
+			{threads: "1", active: "0", total: "0", longest: "0", rows: "0"},
+		}},
+		{field: "digest", groups: []group{
+			{key: statementDigest(db.NewResult(group_columns, group_rows[3:4]).Rows[0]), label: "select sum(amount) from sales where year in (...)",
+				threads: "2", active: "2", total: "61000000000000", longest: "60000000000000", rows: "90500"},
+			{key: statementDigest(db.NewResult(group_columns, group_rows[0:1]).Rows[0]), label: "select * from orders where id = ?",
+				threads: "2", active: "2", total: "7000000000000", longest: "5000000000000", rows: "40"},
+			{threads: "2", active: "0", total: "0", longest: "0", rows: "0"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			result := groupProcesslist(test.field, db.NewResult(group_columns, group_rows))
+			var groups []group
+			for _, row := range result.Rows {
+				groups = append(groups, group{
+					key: row.Get("group_key").String(), label: row.Get("label").String(),
+					threads: row.Get("threads").String(), active: row.Get("active").String(),
+					total: row.Get("total_time").String(), longest: row.Get("max_time").String(), rows: row.Get("rows_examined").String(),
+				})
+			}
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("groups are:\n%+v\nwant:\n%+v", groups, test.groups)
+			}
+		})
+	}
+}
+
+func TestFilterEquals(t *testing.T) {
+	tests := []struct {
+		value string
+		term  string
+	}{
+		{value: "app", term: "state=app"},
+		{value: "", term: "state="},
+		{value: "Sending data", term: `state="Sending data"`},
+		{value: `say "hi"`, term: `state='say "hi"'`},
+		{value: `it's "a"`, term: `state=/it's "a"/`},
+		{value: `a/b`, term: `state="a/b"`},
+		// all the quotes, the value is matched by a regular expression
+		{value: `it's "a/b" (1.5)`, term: `state~/^it's "a\x2fb" \(1\.5\)$/`},
+	}
+	for _, test := range tests {
+		term := filterEquals("state", test.value)
+		if term != test.term {
+			t.Errorf("filterEquals(%q) = %s, want %s", test.value, term, test.term)
+		}
+		// the term matches the value only
+		var filter ProcesslistFilter
+		if err := filter.Set(term); err != nil {
+			t.Errorf("%s: %v", term, err)
+			continue
+		}
+		result := db.NewResult([]string{"state"}, [][]interface{}{{test.value}, {test.value + "x"}, {"x" + test.value}})
+		if filter.Apply(result); len(result.Rows) != 1 || result.Rows[0].Get("state").String() != test.value {
+			t.Errorf("%s matches %d threads, want only %q", term, len(result.Rows), test.value)
+		}
+	}
+}
+
+func TestProcesslistGroupOpen(t *testing.T) {
+	var group ProcesslistGroup
+	var filter ProcesslistFilter
+	if err := filter.Set("cmd!=Sleep"); err != nil {
+		t.Fatal(err)
+	}
+	if group.Back(&filter) {
+		t.Error("back without an opened group")
+	}
+	group.Next()
+	group.Next()
+	if field := group.Field(); field != "host" {
+		t.Fatalf("grouped on %q, want host", field)
+	}
+	groups := groupProcesslist("host", db.NewResult(group_columns, group_rows))
+	if err := group.Open(groups.Rows[0], &filter); err != nil {
+		t.Fatal(err)
+	}
+	if field := group.Field(); len(field) > 0 {
+		t.Errorf("grouped on %q once the group is opened", field)
+	}
+	if expr := filter.String(); expr != "cmd!=Sleep host=10.0.0.5" {
+		t.Errorf("filter is %q, want %q", expr, "cmd!=Sleep host=10.0.0.5")
+	}
+	// the filter shows the threads of the group
+	threads := db.NewResult(group_columns, group_rows)
+	filter.Apply(threads)
+	var ids []string
+	for _, row := range threads.Rows {
+		ids = append(ids, row.Get("conn_id").String())
+	}
+	if want := []string{"1", "2", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("threads of the group are %v, want %v", ids, want)
+	}
+
+	if !group.Back(&filter) {
+		t.Error("no group to go back to")
+	}
+	if field := group.Field(); field != "host" {
+		t.Errorf("grouped on %q, want host", field)
+	}
+	if expr := filter.String(); expr != "cmd!=Sleep" {
+		t.Errorf("filter is %q, want %q", expr, "cmd!=Sleep")
+	}
+	if group.Back(&filter) {
+		t.Error("back twice")
+	}
+
+	// a filter set in the group is kept
+	if err := group.Open(groups.Rows[1], &filter); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.Set("user=report"); err != nil {
+		t.Fatal(err)
+	}
+	group.Back(&filter)
+	if expr := filter.String(); expr != "user=report" {
+		t.Errorf("filter is %q, want %q", expr, "user=report")
+	}
+
+	// the threads without a value
+	group.Next()
+	if err := filter.Set(""); err != nil {
+		t.Fatal(err)
+	}
+	groups = groupProcesslist("db", db.NewResult(group_columns, group_rows))
+	if err := group.Open(groups.Rows[2], &filter); err != nil {
+		t.Fatal(err)
+	}
+	if expr := filter.String(); expr != "db=" {
+		t.Errorf("filter is %q, want %q", expr, "db=")
+	}
+}
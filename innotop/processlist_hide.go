@@ -0,0 +1,121 @@
+package innotop
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// the classes of threads which can be hidden from the processlist
+const (
+	thread_own = iota
+	thread_replication
+	thread_background
+	thread_idle
+	thread_classes
+)
+
+var thread_class_names = [thread_classes]string{"innotopgo", "replication", "background", "idle"}
+
+// ProcesslistHide is the classes of threads hidden from the processlist, it
+// is kept across the refreshes and the server switches.
+type ProcesslistHide struct {
+	mutex  sync.Mutex
+	hidden [thread_classes]bool
+	// own are the connections of innotopgo seen running the processlist
+	// query, the pool uses several of them in turn
+	own map[string]bool
+}
+
+// Toggle hides the class of threads or shows it again
+func (hide *ProcesslistHide) Toggle(class int) {
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	hide.hidden[class] = !hide.hidden[class]
+}
+
+// See records the connections of innotopgo found in a processlist read
+func (hide *ProcesslistHide) See(result *db.Result) {
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	if hide.own == nil {
+		hide.own = map[string]bool{}
+	}
+	for _, row := range result.Rows {
+		if own, _ := row.Get("own").Int64(); own == 1 {
+			hide.own[row.Get("conn_id").String()] = true
+		}
+	}
+}
+
+// Forget drops the connections of innotopgo, they belong to a server that
+// is no longer displayed
+func (hide *ProcesslistHide) Forget() {
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	hide.own = nil
+}
+
+// Own returns whether the row is a connection of innotopgo
+func (hide *ProcesslistHide) Own(row db.Row) bool {
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	return hide.own[row.Get("conn_id").String()]
+}
+
+// Apply removes the rows of the hidden classes and returns how many rows
+// of each class were removed
+func (hide *ProcesslistHide) Apply(result *db.Result) [thread_classes]int {
+	var removed [thread_classes]int
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	rows := result.Rows[:0]
+	for _, row := range result.Rows {
+		if class := hide.class(row); class >= 0 {
+			removed[class]++
+			continue
+		}
+		rows = append(rows, row)
+	}
+	result.Rows = rows
+	return removed
+}
+
+// class returns the first hidden class of the thread of the row, -1 when
+// the row is displayed
+func (hide *ProcesslistHide) class(row db.Row) int {
+	thread_type := row.Get("thread_type").String()
+	switch {
+	case hide.hidden[thread_own] && hide.own[row.Get("conn_id").String()]:
+		return thread_own
+	case hide.hidden[thread_replication] && thread_type == "replication":
+		return thread_replication
+	case hide.hidden[thread_background] && thread_type == "background":
+		return thread_background
+	case hide.hidden[thread_idle] && row.Get("command").String() == "Sleep":
+		return thread_idle
+	}
+	return -1
+}
+
+// Footer returns the number of rows hidden for each hidden class and by the
+// filter, empty when all the threads are displayed
+func (hide *ProcesslistHide) Footer(removed [thread_classes]int, filtered int) string {
+	hide.mutex.Lock()
+	defer hide.mutex.Unlock()
+	var counts []string
+	for class, hidden := range hide.hidden {
+		if hidden {
+			counts = append(counts, fmt.Sprintf("%d %s", removed[class], thread_class_names[class]))
+		}
+	}
+	if filtered > 0 {
+		counts = append(counts, fmt.Sprintf("%d filtered", filtered))
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	return "hidden: " + strings.Join(counts, ", ")
+}
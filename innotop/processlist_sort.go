@@ -0,0 +1,112 @@
+package innotop
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+// sortColumn is a column of GetProcesslist the processlist can be sorted on
+type sortColumn struct {
+	// header is the title of the displayed column carrying the sort mark
+	header  string
+	column  string
+	numeric bool
+}
+
+// sort_columns are the columns selected with <, > in this order, the numeric
+// ones are sorted in descending order unless reversed
+var sort_columns = []sortColumn{
+	{header: "Time", column: "sort_time", numeric: true},
+	{header: "Lock Time", column: "lock_time", numeric: true},
+	{header: "Rows", column: "rows_examined", numeric: true},
+	{header: "Memory", column: "memory", numeric: true},
+	{header: "User", column: "user"},
+	{header: "Db", column: "db"},
+	{header: "Cmd", column: "command"},
+}
+
+// ProcesslistSort is the order of the processlist chosen by the user, it is
+// kept across the refreshes and the server switches.
+type ProcesslistSort struct {
+	mutex   sync.Mutex
+	current int
+	reverse bool
+	// columns are the columns to sort on, sort_columns when nil
+	columns []sortColumn
+}
+
+func (order *ProcesslistSort) sortColumns() []sortColumn {
+	if order.columns == nil {
+		return sort_columns
+	}
+	return order.columns
+}
+
+// Move selects the next sort column, or the previous one when step is
+// negative, and resets the direction.
+func (order *ProcesslistSort) Move(step int) {
+	order.mutex.Lock()
+	defer order.mutex.Unlock()
+	columns := order.sortColumns()
+	order.current = (order.current + step + len(columns)) % len(columns)
+	order.reverse = false
+}
+
+func (order *ProcesslistSort) Reverse() {
+	order.mutex.Lock()
+	defer order.mutex.Unlock()
+	order.reverse = !order.reverse
+}
+
+// Header returns the title of the column with the mark of the direction when
+// the processlist is sorted on it
+func (order *ProcesslistSort) Header(header string) string {
+	column, descending := order.state()
+	if column.header != header {
+		return header
+	}
+	if descending {
+		return header + " ↓"
+	}
+	return header + " ↑"
+}
+
+// Apply sorts the rows of the processlist, the NULL values are always last
+// and the rows keep the order of the server when they are equal.
+func (order *ProcesslistSort) Apply(result *db.Result) {
+	column, descending := order.state()
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		a, b := result.Rows[i].Get(column.column), result.Rows[j].Get(column.column)
+		if a.IsNull() || b.IsNull() {
+			return !a.IsNull() && b.IsNull()
+		}
+		var cmp int
+		if column.numeric {
+			fa, _ := a.Float64()
+			fb, _ := b.Float64()
+			switch {
+			case fa < fb:
+				cmp = -1
+			case fa > fb:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(strings.ToLower(a.String()), strings.ToLower(b.String()))
+		}
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// state returns the sort column and whether the rows are in descending order
+func (order *ProcesslistSort) state() (sortColumn, bool) {
+	order.mutex.Lock()
+	defer order.mutex.Unlock()
+	column := order.sortColumns()[order.current]
+	return column, column.numeric != order.reverse
+}
@@ -0,0 +1,70 @@
+package innotop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestProcesslistSortApply(t *testing.T) {
+	// the values are read from the server as text
+	rows := [][]interface{}{
+		{"1", "9", "bob@10.0.0.5", "Query", nil},
+		{"2", "10", "alice@10.0.0.6", "Sleep", "4096"},
+		{"3", "100", "Carol@10.0.0.7", "Query", "512"},
+		{"4", "10", "alice@10.0.0.8", "Query", nil},
+	}
+	tests := []struct {
+		name    string
+		move    int
+		reverse bool
+		// want are the conn_id of the rows in order
+		want string
+	}{
+		{name: "numeric descending", want: "3 2 4 1"},
+		{name: "numeric reversed", reverse: true, want: "1 2 4 3"},
+		{name: "numeric with NULL last", move: 3, want: "2 3 1 4"},
+		{name: "numeric reversed with NULL last", move: 3, reverse: true, want: "3 2 1 4"},
+		{name: "string ascending case insensitive", move: 4, want: "2 4 1 3"},
+		{name: "string reversed", move: 4, reverse: true, want: "3 1 4 2"},
+		{name: "string keeps the order of the server", move: 6, want: "1 3 4 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := db.NewResult([]string{"conn_id", "sort_time", "user", "command", "memory"}, rows)
+			var order ProcesslistSort
+			order.Move(test.move)
+			if test.reverse {
+				order.Reverse()
+			}
+			order.Apply(result)
+			var ids []string
+			for _, row := range result.Rows {
+				ids = append(ids, row.Get("conn_id").String())
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("order is %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProcesslistSortMove(t *testing.T) {
+	var order ProcesslistSort
+	if header := order.Header("Time"); header != "Time ↓" {
+		t.Errorf("header is %q, want %q", header, "Time ↓")
+	}
+	order.Reverse()
+	if header := order.Header("Time"); header != "Time ↑" {
+		t.Errorf("header is %q, want %q", header, "Time ↑")
+	}
+	// moving resets the direction, the previous column of the first is the last
+	order.Move(-1)
+	if header := order.Header("Cmd"); header != "Cmd ↑" {
+		t.Errorf("header is %q, want %q", header, "Cmd ↑")
+	}
+	if header := order.Header("Time"); header != "Time" {
+		t.Errorf("header is %q, want %q", header, "Time")
+	}
+}
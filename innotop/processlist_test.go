@@ -0,0 +1,100 @@
+package innotop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+var processlist_columns = []string{"command", "thd_id", "conn_id", "pid", "state", "user", "db",
+	"current_statement", "statement_latency", "lock_latency", "sort_time", "lock_time", "rows_examined",
+	"memory", "digest", "thread_type", "own", "full_statement"}
+
+// selectColumns returns the names of the columns of the select list of a
+// statement: the alias of each column, or the column name without its table
+func selectColumns(stmt string) []string {
+	var columns []string
+	depth, start := 0, len("select")
+	add := func(expr string) {
+		expr = strings.TrimSpace(expr)
+		if i := strings.LastIndex(expr, " AS "); i >= 0 {
+			expr = expr[i+len(" AS "):]
+		} else if i := strings.LastIndex(expr, "."); i >= 0 {
+			expr = expr[i+1:]
+		}
+		columns = append(columns, strings.TrimSpace(expr))
+	}
+	for i := start; i < len(stmt); i++ {
+		switch {
+		case stmt[i] == '(':
+			depth++
+		case stmt[i] == ')':
+			depth--
+		case stmt[i] == ',' && depth == 0:
+			add(stmt[start:i])
+			start = i + 1
+		case depth == 0 && strings.HasPrefix(stmt[i:], "from ") && strings.TrimSpace(stmt[i-1:i]) == "":
+			add(stmt[start:i])
+			return columns
+		}
+	}
+	return columns
+}
+
+func TestGetProcesslist(t *testing.T) {
+	tests := []struct {
+		name string
+		caps db.Capabilities
+		// stmt is the statement expected, contains the parts of it when empty
+		stmt     string
+		contains []string
+		excludes []string
+	}{
+		{name: "8.0 with sys", caps: newCapabilities("8.0.36", true, true),
+			contains: []string{"performance_schema.threads", "sys.format_statement(", "format_pico_time(esc.TIMER_WAIT)"},
+			excludes: []string{"sys.format_time("}},
+		{name: "8.0.11 with sys", caps: newCapabilities("8.0.11", true, true),
+			contains: []string{"performance_schema.threads", "sys.format_time(esc.TIMER_WAIT)"},
+			excludes: []string{"format_pico_time("}},
+		{name: "8.0 without sys", caps: newCapabilities("8.0.36", true, false),
+			contains: []string{"performance_schema.threads", "pps.PROCESSLIST_INFO AS current_statement", "format_pico_time("},
+			excludes: []string{"sys."}},
+		{name: "5.7 with sys", caps: newCapabilities("5.7.44-log", true, true),
+			contains: []string{"performance_schema.threads", "sys.format_statement(", "sys.format_time(esc.LOCK_TIME)"},
+			excludes: []string{"format_pico_time("}},
+		{name: "5.7 without performance_schema", caps: newCapabilities("5.7.44", false, false), stmt: mysql_processlist},
+		{name: "MariaDB", caps: newCapabilities("10.6.12-MariaDB", true, true), stmt: mariadb_queries.processlist},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stmt := processlistStatement(test.caps)
+			if len(test.stmt) > 0 && stmt != test.stmt {
+				t.Errorf("statement is:\n%s\nwant:\n%s", stmt, test.stmt)
+			}
+			for _, part := range test.contains {
+				if !strings.Contains(stmt, part) {
+					t.Errorf("statement does not contain %q", part)
+				}
+			}
+			for _, part := range test.excludes {
+				if strings.Contains(stmt, part) {
+					t.Errorf("statement contains %q", part)
+				}
+			}
+
+			// every variant returns the columns read by the screen
+			if columns := selectColumns(stmt); strings.Join(columns, ",") != strings.Join(processlist_columns, ",") {
+				t.Errorf("columns are %v, want %v", columns, processlist_columns)
+			}
+
+			// GetProcesslist runs the statement of the server, the fake
+			// knows no other one
+			fake := db.NewFake()
+			fake.Set(stmt, processlist_columns)
+			if _, err := GetProcesslist(fake, test.caps); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
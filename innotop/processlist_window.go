@@ -0,0 +1,57 @@
+package innotop
+
+import (
+	"sync"
+
+	"github.com/mum4k/termdash/mouse"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// ProcesslistWindow is the text widget of the processlist. It remembers its
+// height so that the processlist only writes the rows around the selected
+// thread, and reports the line clicked with the mouse.
+type ProcesslistWindow struct {
+	*text.Text
+
+	mutex  sync.Mutex
+	height int
+	// OnClick is called with the line clicked, 0 being the first line
+	OnClick func(line int)
+}
+
+func NewProcesslistWindow(main_window *text.Text) *ProcesslistWindow {
+	return &ProcesslistWindow{Text: main_window}
+}
+
+// Height returns the number of lines of the last draw, 0 before the first
+func (window *ProcesslistWindow) Height() int {
+	window.mutex.Lock()
+	defer window.mutex.Unlock()
+	return window.height
+}
+
+// Draw implements widgetapi.Widget.Draw.
+func (window *ProcesslistWindow) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	window.mutex.Lock()
+	window.height = cvs.Area().Dy()
+	window.mutex.Unlock()
+	return window.Text.Draw(cvs, meta)
+}
+
+// Keyboard implements widgetapi.Widget.Keyboard, the text is not scrolled
+// as the keys move the selection.
+func (window *ProcesslistWindow) Keyboard(k *terminalapi.Keyboard, meta *widgetapi.EventMeta) error {
+	return nil
+}
+
+// Mouse implements widgetapi.Widget.Mouse, the position of the event is
+// relative to the widget.
+func (window *ProcesslistWindow) Mouse(m *terminalapi.Mouse, meta *widgetapi.EventMeta) error {
+	if m.Button == mouse.ButtonLeft && window.OnClick != nil {
+		window.OnClick(m.Position.Y)
+	}
+	return nil
+}
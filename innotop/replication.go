@@ -1,7 +1,6 @@
 package innotop
 
 import (
-	"database/sql"
 	"context"
 	"fmt"
 	"time"
@@ -19,34 +18,27 @@ import (
 	"github.com/mum4k/termdash/widgets/linechart"
 )
 
-func GetReplicaStatus(mydb *sql.DB) ([]string, [][]string, error) {
-	stmt := `SHOW REPLICA STATUS;`
+func GetReplicaStatus(mydb db.Querier, caps db.Capabilities) ([]string, [][]string, error) {
+	stmt := queries(caps).replica_status
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
+	cols, data = normalizeReplicaStatus(normalizeColumns(cols), data)
 
 	return cols, data, nil
 }
 
-func GetSourceStatus(mydb *sql.DB) ([]string, [][]string, error) {
-	stmt := `SHOW REPLICAS;`
+func GetSourceStatus(mydb db.Querier, caps db.Capabilities) ([]string, [][]string, error) {
+	stmt := queries(caps).replicas
 
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
+	cols, data, err := db.GetData(mydb, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return cols, data, nil
+	return normalizeColumns(cols), data, nil
 }
 
 func refresh_replication_info(t *tcell.Terminal, cancel context.CancelFunc, ctx context.Context, interval time.Duration, fn func() error) {
@@ -85,7 +77,17 @@ func convertSecondsToDuration(seconds float64) string {
 	}
 }
 
-func DisplayReplication(mydb *sql.DB, c *container.Container, t *tcell.Terminal) (keyboard.Key, error) {
+func newReplicationLagGraph() (*linechart.LineChart, error) {
+	return linechart.New(
+		linechart.AxesCellOpts(cell.FgColor(cell.ColorRed)),
+		linechart.YLabelCellOpts(cell.FgColor(cell.ColorGreen)),
+		linechart.XLabelCellOpts(cell.FgColor(cell.ColorGreen)),
+		linechart.YAxisFormattedValues(convertSecondsToDuration),
+		linechart.YAxisCustomScale(0, 5),
+	)
+}
+
+func DisplayReplication(servers *Servers, c *container.Container, t *tcell.Terminal, interval time.Duration) (keyboard.Key, error) {
 	ctxmem, cancel := context.WithCancel(context.Background())
 	k := keyboard.KeyBackspace2
 	sourceStatusWidget, err := text.New()
@@ -104,13 +106,7 @@ func DisplayReplication(mydb *sql.DB, c *container.Container, t *tcell.Terminal)
 
 	// graph replication lag
 	// as two sparkline graphs on top of each other, as linechart does not seem to support labelled lines
-	replication_lag_graph, err := linechart.New(
-		linechart.AxesCellOpts(cell.FgColor(cell.ColorRed)),
-		linechart.YLabelCellOpts(cell.FgColor(cell.ColorGreen)),
-		linechart.XLabelCellOpts(cell.FgColor(cell.ColorGreen)),
-		linechart.YAxisFormattedValues(convertSecondsToDuration),
-		linechart.YAxisCustomScale(0, 5),
-	)
+	replication_lag_graph, err := newReplicationLagGraph()
 	if err != nil {
 		cancel()
 		return k, err
@@ -121,13 +117,35 @@ func DisplayReplication(mydb *sql.DB, c *container.Container, t *tcell.Terminal)
 	// Initialize the map
 	graphValues = make(map[string][]float64)
 
-	go refresh_replication_info(t, cancel, ctxmem, 1*time.Second, func() error {
-		cols, data, err := GetReplicaStatus(mydb)
+	var last_server *Server
+
+	go refresh_replication_info(t, cancel, ctxmem, interval, servers.Refresh(func(srv *Server) error {
+		if len(srv.Unavailable(ScreenReplication)) > 0 {
+			// the quitter goes back to the processlist
+			return nil
+		}
+		if srv != last_server {
+			// the channels and their lag history belong to the previous server
+			replica_info = make(map[string]string)
+			source_info = make(map[string]string)
+			graphValues = make(map[string][]float64)
+			if last_server != nil {
+				new_graph, err := newReplicationLagGraph()
+				if err != nil {
+					return err
+				}
+				replication_lag_graph = new_graph
+				c.Update("replication_lag_graph", container.PlaceWidget(replication_lag_graph))
+			}
+			last_server = srv
+		}
+		mydb := srv.DB
+		cols, data, err := GetReplicaStatus(mydb, srv.Capabilities)
 		if err != nil {
 			return err
 		}
 
-		sourceCols, source_data, err := GetSourceStatus(mydb)
+		sourceCols, source_data, err := GetSourceStatus(mydb, srv.Capabilities)
 		if err != nil {
 			return err
 		}
@@ -268,7 +286,7 @@ func DisplayReplication(mydb *sql.DB, c *container.Container, t *tcell.Terminal)
 		}
 
 		return nil
-	})
+	}))
 
 	c.Update("dyn_top_container",
 		container.SplitHorizontal(
@@ -312,6 +330,13 @@ func DisplayReplication(mydb *sql.DB, c *container.Container, t *tcell.Terminal)
 			k = k2.Key
 			cancel()
 			return
+		} else if servers.HandleKey(k2) {
+			if len(servers.Current().Unavailable(ScreenReplication)) > 0 {
+				// the processlist tells why
+				k = keyboard.KeyBackspace2
+				cancel()
+			}
+			return
 		} else {
 			return
 		}
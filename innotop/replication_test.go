@@ -0,0 +1,130 @@
+package innotop
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestGetReplicaStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		caps db.Capabilities
+		stmt string
+		cols []string
+		row  []interface{}
+		// want are the values of the normalized columns
+		want map[string]string
+	}{
+		{
+			name: "8.0.22",
+			caps: newCapabilities("8.0.36", true, true),
+			stmt: "SHOW REPLICA STATUS",
+			cols: []string{"Source_Host", "Replica_IO_Running", "Replica_SQL_Running", "Seconds_Behind_Source", "Auto_Position", "Channel_Name"},
+			row:  []interface{}{"db1", "Yes", "Yes", 3, 1, ""},
+			want: map[string]string{"Source_Host": "db1", "Replica_IO_Running": "Yes", "Seconds_Behind_Source": "3", "Auto_Position": "1"},
+		},
+		{
+			name: "8.0.21",
+			caps: newCapabilities("8.0.21", true, true),
+			stmt: "SHOW SLAVE STATUS",
+			cols: []string{"Master_Host", "Slave_IO_Running", "Slave_SQL_Running", "Seconds_Behind_Master", "Auto_Position", "Channel_Name", "Get_master_public_key"},
+			row:  []interface{}{"db1", "Yes", "No", nil, 0, "", 0},
+			want: map[string]string{"Source_Host": "db1", "Replica_SQL_Running": "No", "Auto_Position": "0", "Get_Source_public_key": "0"},
+		},
+		{
+			name: "5.7",
+			caps: newCapabilities("5.7.44-log", true, true),
+			stmt: "SHOW SLAVE STATUS",
+			cols: []string{"Master_Host", "Slave_IO_Running", "Slave_SQL_Running", "Seconds_Behind_Master", "Auto_Position"},
+			row:  []interface{}{"db1", "Connecting", "Yes", 12, 1},
+			want: map[string]string{"Source_Host": "db1", "Replica_IO_Running": "Connecting", "Seconds_Behind_Source": "12", "Auto_Position": "1"},
+		},
+		{
+			name: "MariaDB with GTID",
+			caps: newCapabilities("10.6.12-MariaDB", true, true),
+			stmt: "SHOW ALL SLAVES STATUS",
+			cols: []string{"Connection_name", "Master_Host", "Slave_IO_Running", "Seconds_Behind_Master", "Using_Gtid", "Gtid_IO_Pos", "Gtid_Slave_Pos"},
+			row:  []interface{}{"east", "db1", "Yes", 0, "Slave_Pos", "0-1-42", "0-1-41"},
+			want: map[string]string{"Channel_Name": "east", "Source_Host": "db1", "Auto_Position": "1",
+				"Retrieved_Gtid_Set": "0-1-42", "Executed_Gtid_Set": "0-1-41"},
+		},
+		{
+			name: "MariaDB without GTID",
+			caps: newCapabilities("10.6.12-MariaDB", true, true),
+			stmt: "SHOW ALL SLAVES STATUS",
+			cols: []string{"Connection_name", "Master_Host", "Using_Gtid"},
+			row:  []interface{}{"", "db1", "No"},
+			want: map[string]string{"Channel_Name": "", "Source_Host": "db1", "Auto_Position": "0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := db.NewFake()
+			fake.Set(test.stmt, test.cols, test.row)
+			cols, data, err := GetReplicaStatus(fake, test.caps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 1 {
+				t.Fatalf("got %d rows, want 1", len(data))
+			}
+			values := make(map[string]string)
+			for i, col := range cols {
+				values[col] = data[0][i]
+			}
+			for col, want := range test.want {
+				if value, ok := values[col]; !ok || value != want {
+					t.Errorf("%s is %q (found: %v), want %q", col, value, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSourceStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		caps db.Capabilities
+		stmt string
+		cols []string
+		want []string
+	}{
+		{name: "8.0.22", caps: newCapabilities("8.0.36", true, true), stmt: "SHOW REPLICAS",
+			cols: []string{"Server_Id", "Host", "Port", "Source_Id", "Replica_UUID"},
+			want: []string{"Server_Id", "Host", "Port", "Source_Id", "Replica_UUID"}},
+		{name: "5.7", caps: newCapabilities("5.7.44", true, true), stmt: "SHOW SLAVE HOSTS",
+			cols: []string{"Server_id", "Host", "Port", "Master_id", "Slave_UUID"},
+			want: []string{"Server_Id", "Host", "Port", "Source_Id", "Replica_UUID"}},
+		{name: "MariaDB", caps: newCapabilities("10.6.12-MariaDB", true, true), stmt: "SHOW SLAVE HOSTS",
+			cols: []string{"Server_id", "Host", "Port", "Master_id"},
+			want: []string{"Server_Id", "Host", "Port", "Source_Id"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := db.NewFake()
+			fake.Set(test.stmt, test.cols)
+			cols, _, err := GetSourceStatus(fake, test.caps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cols, test.want) {
+				t.Errorf("columns are %v, want %v", cols, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeReplicaStatus(t *testing.T) {
+	// Auto_Position is kept when the server has it
+	cols, data := normalizeReplicaStatus([]string{"Using_Gtid", "Auto_Position"}, [][]string{{"Current_Pos", "0"}})
+	if !reflect.DeepEqual(cols, []string{"Using_Gtid", "Auto_Position"}) || !reflect.DeepEqual(data, [][]string{{"Current_Pos", "0"}}) {
+		t.Errorf("normalizeReplicaStatus changed the status: %v %v", cols, data)
+	}
+	// no replication channel
+	cols, data = normalizeReplicaStatus([]string{"Channel_Name", "Using_Gtid"}, nil)
+	if !reflect.DeepEqual(cols, []string{"Channel_Name", "Using_Gtid", "Auto_Position"}) || len(data) != 0 {
+		t.Errorf("normalizeReplicaStatus without rows: %v %v", cols, data)
+	}
+}
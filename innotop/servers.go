@@ -0,0 +1,268 @@
+package innotop
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// Server is a monitored MySQL server and its connection pool
+type Server struct {
+	Name string
+	DB   db.Querier
+	// Socket is the unix socket used to connect, if any
+	Socket string
+
+	Brand    string
+	Version  string
+	Hostname string
+	Port     string
+	// Capabilities are probed once by LoadInfo
+	Capabilities db.Capabilities
+	// Replay is set when the server is played back from a recording
+	Replay *db.Replay
+
+	reconnect db.Reconnector
+	// counter counts the statements run on the server, set by NewServers
+	counter *db.CostQuerier
+	// cost of the statements of the last refresh
+	cost db.Cost
+}
+
+// LoadInfo retrieves the information displayed in the header bar
+func (srv *Server) LoadInfo() error {
+	cols, data, err := db.GetServerInfo(srv.DB)
+	if err != nil {
+		return fmt.Errorf("%s: %v", srv.Name, err)
+	}
+	for _, row := range data {
+		srv.Brand = row[0]
+		srv.Version = row[1]
+		srv.Hostname = row[2]
+		srv.Port = row[3]
+		srv.Capabilities = db.NewCapabilities(cols, row)
+	}
+	return nil
+}
+
+// Unavailable returns why the screen cannot be displayed for the server, or
+// an empty string when it can.
+func (srv *Server) Unavailable(screen string) string {
+	caps := srv.Capabilities
+	if srv.Replay != nil && !srv.Replay.Recorded(screen_modes[screen]) {
+		return "it was not displayed during the recording"
+	}
+	switch screen {
+	case ScreenProcesslist, ScreenReplication:
+		// each flavor has its own query set
+		return ""
+	}
+	if !caps.PerformanceSchema {
+		return "performance_schema is disabled"
+	}
+	switch screen {
+	case ScreenInnoDB:
+		if !caps.SysSchema {
+			return "the sys schema is missing"
+		}
+		if !caps.LogStatus() {
+			return "performance_schema.log_status requires MySQL 8.0.17 or later"
+		}
+	case ScreenMemory:
+		if !caps.SysSchema {
+			return "the sys schema is missing"
+		}
+		if !caps.FormatPicoTime() {
+			return "format_bytes() requires MySQL 8.0.16 or later"
+		}
+	case ScreenLocking:
+		if !caps.SysSchema {
+			return "the sys schema is missing"
+		}
+		if !caps.FormatPicoTime() {
+			return "format_pico_time() requires MySQL 8.0.16 or later"
+		}
+	case screen_explain, screen_thread_details:
+		if !caps.SysSchema {
+			return "the sys schema is missing"
+		}
+	case ScreenErrorlog:
+		if !caps.ErrorLog {
+			return "performance_schema.error_log requires MySQL 8.0.22 or later"
+		}
+	}
+	return ""
+}
+
+// Disconnected returns whether the connection to the server is lost and
+// the time left before the next attempt to reconnect.
+func (srv *Server) Disconnected() (bool, time.Duration) {
+	lost, retry_in, _ := srv.reconnect.Status()
+	return lost, retry_in
+}
+
+const replay_seek = 1 * time.Minute
+
+// Servers is the list of monitored servers and the one currently displayed
+type Servers struct {
+	mutex   sync.Mutex
+	list    []*Server
+	current int
+	// OnSwitch is called after the current server changed
+	OnSwitch func(srv *Server)
+	// OnRefresh is called after a refresh of the current screen
+	OnRefresh func(srv *Server)
+	// Interval is the refresh interval the monitor cost is compared to
+	Interval time.Duration
+}
+
+// NewServers counts the cost of the statements run on each server, the
+// monitor cost of a refresh is charged to its server only
+func NewServers(list []*Server) *Servers {
+	for _, srv := range list {
+		srv.counter = db.NewCostQuerier(srv.DB)
+		srv.DB = srv.counter
+	}
+	return &Servers{list: list}
+}
+
+func (servers *Servers) Current() *Server {
+	servers.mutex.Lock()
+	defer servers.mutex.Unlock()
+	return servers.list[servers.current]
+}
+
+// DB returns the connection pool of the current server
+func (servers *Servers) DB() db.Querier {
+	return servers.Current().DB
+}
+
+func (servers *Servers) Len() int {
+	return len(servers.list)
+}
+
+func (servers *Servers) List() []*Server {
+	return servers.list
+}
+
+// Position returns the 1-based position of the current server
+func (servers *Servers) Position() int {
+	servers.mutex.Lock()
+	defer servers.mutex.Unlock()
+	return servers.current + 1
+}
+
+func (servers *Servers) Select(i int) bool {
+	servers.mutex.Lock()
+	if i < 0 || i >= len(servers.list) || i == servers.current {
+		servers.mutex.Unlock()
+		return false
+	}
+	servers.current = i
+	srv := servers.list[i]
+	servers.mutex.Unlock()
+	if servers.OnSwitch != nil {
+		servers.OnSwitch(srv)
+	}
+	return true
+}
+
+// HandleKey switches the current server: <Tab> cycles through the servers,
+// <1> to <9> pick one. It returns true when the key changed the current
+// server.
+func (servers *Servers) HandleKey(k *terminalapi.Keyboard) bool {
+	if replay := servers.Replay(); replay != nil {
+		if handleReplayKey(replay, k) {
+			return false
+		}
+	}
+	if len(servers.list) < 2 {
+		return false
+	}
+	current := servers.Position() - 1
+	switch {
+	case k.Key == keyboard.KeyTab:
+		return servers.Select((current + 1) % len(servers.list))
+	case k.Key >= '1' && k.Key <= '9':
+		return servers.Select(int(k.Key - '1'))
+	}
+	return false
+}
+
+// Refresh wraps the refresh function of a screen so that it is skipped while
+// the connection to the current server is lost. The errors due to a lost
+// connection are not returned, the screen keeps its state and is refreshed
+// again once the server is back.
+func (servers *Servers) Refresh(fn func(srv *Server) error) func() error {
+	// server and time of the playback clock at the last refresh of a replay
+	var replay_srv *Server
+	var replay_at time.Time
+	return func() error {
+		srv := servers.Current()
+		if !srv.reconnect.Check(srv.DB) {
+			return nil
+		}
+		if srv.Replay != nil {
+			// a paused replay keeps the screen as it is
+			now := srv.Replay.Now()
+			if srv == replay_srv && now.Equal(replay_at) {
+				return nil
+			}
+			replay_srv, replay_at = srv, now
+		}
+		before := srv.counter.Cost()
+		err := fn(srv)
+		if err != nil && srv.reconnect.Lost(err) {
+			return nil
+		}
+		servers.mutex.Lock()
+		srv.cost = srv.counter.Cost().Sub(before)
+		servers.mutex.Unlock()
+		if err == nil && servers.OnRefresh != nil {
+			servers.OnRefresh(srv)
+		}
+		return err
+	}
+}
+
+// Cost returns the time spent by the statements of the last refresh of the
+// current server and their number
+func (servers *Servers) Cost() db.Cost {
+	servers.mutex.Lock()
+	defer servers.mutex.Unlock()
+	return servers.list[servers.current].cost
+}
+
+// Replay returns the recording played back, nil when monitoring servers
+func (servers *Servers) Replay() *db.Replay {
+	return servers.list[0].Replay
+}
+
+// handleReplayKey controls the playback: <p> pauses, <.> and <,> step
+// forward and backward, <+> and <-> change the speed, <]> and <[> seek one
+// minute forward and backward.
+func handleReplayKey(replay *db.Replay, k *terminalapi.Keyboard) bool {
+	switch k.Key {
+	case 'p':
+		replay.TogglePause()
+	case '.':
+		replay.Step(true)
+	case ',':
+		replay.Step(false)
+	case '+':
+		replay.Faster(true)
+	case '-':
+		replay.Faster(false)
+	case ']':
+		replay.Seek(replay_seek)
+	case '[':
+		replay.Seek(-replay_seek)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package innotop
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestServersCost(t *testing.T) {
+	fake := func() *db.Fake {
+		fake := db.NewFake()
+		fake.Set("select 1", []string{"1"}, []interface{}{1})
+		return fake
+	}
+	db1 := &Server{Name: "db1", DB: fake()}
+	db2 := &Server{Name: "db2", DB: fake()}
+	servers := NewServers([]*Server{db1, db2})
+	queries := 2
+	refresh := servers.Refresh(func(srv *Server) error {
+		for i := 0; i < queries; i++ {
+			if _, err := srv.DB.QueryContext(context.Background(), "select 1"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err := refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if cost := servers.Cost(); cost.Queries != 2 {
+		t.Errorf("%d statements charged to db1, want 2", cost.Queries)
+	}
+
+	// the statements run on db2 in the background are not charged to db1
+	for i := 0; i < 5; i++ {
+		if _, err := db2.DB.QueryContext(context.Background(), "select 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queries = 3
+	if err := refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if cost := servers.Cost(); cost.Queries != 3 {
+		t.Errorf("%d statements charged to db1, want 3", cost.Queries)
+	}
+
+	servers.Select(1)
+	queries = 1
+	if err := refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if cost := servers.Cost(); cost.Queries != 1 {
+		t.Errorf("%d statements charged to db2, want 1", cost.Queries)
+	}
+}
@@ -0,0 +1,329 @@
+package innotop
+
+import (
+	"strings"
+	"unicode"
+)
+
+// the kinds of the tokens of a statement, used to highlight them
+const (
+	sql_word = iota
+	sql_keyword
+	sql_string
+	sql_number
+	sql_comment
+	sql_punct
+)
+
+// sql_wrap_width is the column after which the lists, like the values of
+// an IN, continue on the next line
+const sql_wrap_width = 80
+
+// sql_indent is the indentation of a level of the formatted statement
+const sql_indent = "  "
+
+var sql_keywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`ADD ALL ALTER ANALYZE AND ANY AS ASC BETWEEN BY CALL CASE CAST
+		CHECK COLLATE COLUMN CONSTRAINT CONVERT CREATE CROSS CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP
+		DATABASE DEFAULT DELAYED DELETE DESC DESCRIBE DISTINCT DISTINCTROW DIV DO DROP DUAL DUPLICATE
+		ELSE ELSEIF END ESCAPE EXISTS EXPLAIN FALSE FOR FORCE FOREIGN FROM FULL FULLTEXT GROUP HAVING
+		HIGH_PRIORITY IF IGNORE IN INDEX INNER INSERT INTERVAL INTO IS JOIN KEY LATERAL LEFT LIKE LIMIT
+		LOCK LOW_PRIORITY MATCH MOD NATURAL NOT NOWAIT NULL OFFSET ON OR ORDER OUTER OVER PARTITION
+		PRIMARY RECURSIVE REGEXP REPLACE RIGHT RLIKE ROLLUP SELECT SET SHARE SHOW SKIP
+		SQL_BIG_RESULT SQL_CALC_FOUND_ROWS SQL_NO_CACHE SQL_SMALL_RESULT STRAIGHT_JOIN TABLE THEN TO
+		TRUE UNION UNIQUE UPDATE USE USING VALUES WHEN WHERE WINDOW WITH XOR LOCKED KEY_BLOCK_SIZE`) {
+		sql_keywords[keyword] = true
+	}
+}
+
+// sql_clauses are the keywords starting a new line, at the level of their
+// statement
+var sql_clauses = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "UNION": true, "INSERT": true, "REPLACE": true, "VALUES": true, "UPDATE": true,
+	"SET": true, "DELETE": true, "WITH": true, "WINDOW": true, "FOR": true, "ON": true,
+	"JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "CROSS": true, "NATURAL": true,
+	"STRAIGHT_JOIN": true,
+}
+
+// sql_joined are the keywords continuing a clause started by the previous
+// one, like the JOIN of LEFT JOIN or the UPDATE of FOR UPDATE
+var sql_joined = map[string]string{
+	"JOIN":   "LEFT RIGHT INNER CROSS NATURAL OUTER STRAIGHT_JOIN",
+	"OUTER":  "LEFT RIGHT FULL NATURAL",
+	"UPDATE": "FOR KEY",
+	"SET":    "CHARACTER",
+	"ON":     "DUPLICATE",
+	"SELECT": "INSERT REPLACE UNION ALL DISTINCT",
+	"FOR":    "UPDATE",
+}
+
+// sql_paren_space are the keywords followed by a space before a parenthesis,
+// the other words being function names
+var sql_paren_space = map[string]bool{
+	"IN": true, "VALUES": true, "AS": true, "FROM": true, "JOIN": true, "ON": true, "AND": true,
+	"OR": true, "NOT": true, "EXISTS": true, "SELECT": true, "WHERE": true, "USING": true,
+	"INTO": true, "UNION": true, "ALL": true, "ANY": true, "SET": true, "BY": true, "WITH": true,
+	"THEN": true, "ELSE": true, "WHEN": true, "IS": true, "LIKE": true, "BETWEEN": true,
+	"HAVING": true, "LATERAL": true, "TABLE": true,
+}
+
+type sqlToken struct {
+	kind int
+	text string
+}
+
+// sqlSegment is a part of a line of a formatted statement, with the kind of
+// its tokens
+type sqlSegment struct {
+	kind int
+	text string
+}
+
+// tokenizeSQL splits a statement into words, literals, comments and
+// punctuation, the spaces being dropped
+func tokenizeSQL(stmt string) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			i++
+			for i < len(runes) {
+				if runes[i] == '\\' && r != '`' {
+					i += 2
+					continue
+				}
+				if runes[i] == r {
+					// a doubled quote is part of the literal
+					if i+1 < len(runes) && runes[i+1] == r {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			if i > len(runes) {
+				i = len(runes)
+			}
+			kind := sql_string
+			if r == '`' {
+				kind = sql_word
+			}
+			tokens = append(tokens, sqlToken{kind, string(runes[start:i])})
+			continue
+		case r == '#' || (r == '-' && i+2 < len(runes) && runes[i+1] == '-' && unicode.IsSpace(runes[i+2])):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, sqlToken{sql_comment, string(runes[start:i])})
+			continue
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				i = len(runes)
+			} else {
+				i += 2 + len([]rune(string(runes[i+2:])[:end])) + 2
+			}
+			tokens = append(tokens, sqlToken{sql_comment, string(runes[start:i])})
+			continue
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{sql_number, string(runes[start:i])})
+			continue
+		case unicode.IsLetter(r) || r == '_' || r == '$' || r == '@':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_$@", runes[i])) {
+				i++
+			}
+			word := string(runes[start:i])
+			kind := sql_word
+			if sql_keywords[strings.ToUpper(word)] {
+				kind = sql_keyword
+			}
+			tokens = append(tokens, sqlToken{kind, word})
+			continue
+		case strings.ContainsRune("<>=!|&+-*/%^~:", r):
+			for i < len(runes) && strings.ContainsRune("<>=!|&:", runes[i]) && i-start < 3 {
+				i++
+			}
+			if i == start {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, sqlToken{sql_punct, string(runes[start:i])})
+	}
+	return tokens
+}
+
+// sqlFormatter lays the tokens of a statement out on indented lines
+type sqlFormatter struct {
+	lines  [][]sqlSegment
+	column int
+	indent int
+	// parens tells for each open parenthesis if it holds a subquery
+	parens []bool
+	// clauses is the clause of the statement at each subquery level
+	clauses []string
+	prev    sqlToken
+	// between is true from a BETWEEN to its AND
+	between bool
+}
+
+// FormatSQL returns the lines of the statement, one per clause with the
+// subqueries indented and the long lists wrapped
+func FormatSQL(stmt string) [][]sqlSegment {
+	f := &sqlFormatter{clauses: []string{""}}
+	tokens := tokenizeSQL(stmt)
+	for i, token := range tokens {
+		next := sqlToken{}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		f.add(token, next)
+	}
+	// a comment ending the statement leaves an empty line
+	for n := len(f.lines); n > 0 && strings.TrimSpace(segmentsText(f.lines[n-1])) == ""; n-- {
+		f.lines = f.lines[:n-1]
+	}
+	return f.lines
+}
+
+// statementLevel tells whether the tokens are at the level of a statement,
+// and not in the arguments of a function or in a list
+func (f *sqlFormatter) statementLevel() bool {
+	return len(f.parens) == 0 || f.parens[len(f.parens)-1]
+}
+
+func (f *sqlFormatter) add(token sqlToken, next sqlToken) {
+	upper := strings.ToUpper(token.text)
+	prev_upper := strings.ToUpper(f.prev.text)
+	switch {
+	case token.kind == sql_keyword && sql_clauses[upper] && f.statementLevel():
+		joined := strings.Contains(" "+sql_joined[upper]+" ", " "+prev_upper+" ") && f.prev.kind == sql_keyword
+		// LEFT(), REPLACE() and INSERT() are also functions, like VALUES()
+		// in ON DUPLICATE KEY UPDATE
+		function := next.text == "(" && (upper == "LEFT" || upper == "RIGHT" || upper == "REPLACE" || upper == "INSERT" ||
+			(upper == "VALUES" && f.clauses[len(f.clauses)-1] == "ON"))
+		if !joined && !function {
+			indent := f.indent
+			if upper == "ON" && !strings.EqualFold(next.text, "DUPLICATE") {
+				indent++
+			}
+			f.newLine(indent)
+			f.clauses[len(f.clauses)-1] = upper
+		}
+		f.write(token, next)
+	case token.kind == sql_keyword && upper == "BETWEEN":
+		f.between = true
+		f.write(token, next)
+	case token.kind == sql_keyword && upper == "AND" && f.between:
+		f.between = false
+		f.write(token, next)
+	case token.kind == sql_keyword && (upper == "AND" || upper == "OR") && f.statementLevel() &&
+		f.clauses[len(f.clauses)-1] != "SELECT":
+		f.newLine(f.indent + 1)
+		f.write(token, next)
+	case token.text == "(":
+		f.write(token, next)
+		subquery := strings.EqualFold(next.text, "SELECT") || strings.EqualFold(next.text, "WITH")
+		f.parens = append(f.parens, subquery)
+		if subquery {
+			f.indent++
+			f.clauses = append(f.clauses, "")
+		}
+	case token.text == ")":
+		if len(f.parens) > 0 {
+			subquery := f.parens[len(f.parens)-1]
+			f.parens = f.parens[:len(f.parens)-1]
+			if subquery {
+				f.indent--
+				f.clauses = f.clauses[:len(f.clauses)-1]
+				f.newLine(f.indent)
+			}
+		}
+		f.write(token, next)
+	case token.text == ",":
+		f.write(token, next)
+		if f.statementLevel() && (f.clauses[len(f.clauses)-1] == "SELECT" || f.clauses[len(f.clauses)-1] == "SET") {
+			f.newLine(f.indent + 1)
+		} else if !f.statementLevel() && f.column > sql_wrap_width {
+			// the values of a long list, like the ones of an IN
+			f.newLine(f.indent + 2)
+		}
+	case token.kind == sql_comment:
+		f.write(token, next)
+		if strings.HasPrefix(token.text, "#") || strings.HasPrefix(token.text, "--") {
+			f.newLine(f.indent)
+		}
+	default:
+		f.write(token, next)
+	}
+}
+
+// newLine starts a line at the indentation level, an empty line is reused
+func (f *sqlFormatter) newLine(indent int) {
+	if indent < 0 {
+		indent = 0
+	}
+	prefix := strings.Repeat(sql_indent, indent)
+	if n := len(f.lines); n > 0 && len(f.lines[n-1]) <= 1 && strings.TrimSpace(segmentsText(f.lines[n-1])) == "" {
+		f.lines[n-1] = []sqlSegment{{sql_word, prefix}}
+	} else {
+		f.lines = append(f.lines, []sqlSegment{{sql_word, prefix}})
+	}
+	f.column = len(prefix)
+	f.prev = sqlToken{}
+}
+
+// write appends the token to the current line, with a space when the
+// previous token and this one need one
+func (f *sqlFormatter) write(token sqlToken, next sqlToken) {
+	if len(f.lines) == 0 {
+		f.lines = append(f.lines, []sqlSegment{{sql_word, ""}})
+	}
+	line := &f.lines[len(f.lines)-1]
+	if f.prev.text != "" && f.spaced(token) {
+		*line = append(*line, sqlSegment{sql_word, " "})
+		f.column++
+	}
+	*line = append(*line, sqlSegment{token.kind, token.text})
+	f.column += len([]rune(token.text))
+	f.prev = token
+}
+
+// spaced tells whether a space separates the previous token from token
+func (f *sqlFormatter) spaced(token sqlToken) bool {
+	switch {
+	case f.prev.text == "(" || f.prev.text == "." || f.prev.text == "@":
+		return false
+	case token.text == ")" || token.text == "," || token.text == "." || token.text == ";":
+		return false
+	case token.text == "(":
+		if f.prev.kind == sql_keyword {
+			return sql_paren_space[strings.ToUpper(f.prev.text)]
+		}
+		return f.prev.kind != sql_word
+	}
+	return true
+}
+
+func segmentsText(segments []sqlSegment) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteString(segment.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package innotop
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update_golden = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// TestFormatSQL formats the statements of testdata/sqlformat/*.sql and
+// compares them with the .golden files, go test -run TestFormatSQL -update
+// rewrites them
+func TestFormatSQL(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "sqlformat", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no statement in testdata/sqlformat")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".sql")
+		t.Run(name, func(t *testing.T) {
+			stmt, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var lines []string
+			for _, segments := range FormatSQL(string(stmt)) {
+				lines = append(lines, segmentsText(segments))
+			}
+			formatted := strings.Join(lines, "\n") + "\n"
+			golden := strings.TrimSuffix(file, ".sql") + ".golden"
+			if *update_golden {
+				if err := ioutil.WriteFile(golden, []byte(formatted), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != string(want) {
+				t.Errorf("formatted statement:\n%s\nwant:\n%s", formatted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package innotop
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// statement_ps reads the statement of a thread from performance_schema,
+// PROCESSLIST_INFO is the full text of the running statement while SQL_TEXT
+// is limited to performance_schema_max_sql_text_length but is kept once the
+// statement is over
+const statement_ps = `select t.PROCESSLIST_DB AS db, t.PROCESSLIST_INFO AS info, esc.SQL_TEXT AS sql_text
+                        from performance_schema.threads t
+                        left join performance_schema.events_statements_current esc
+                          on esc.THREAD_ID = t.THREAD_ID`
+
+// statement_is reads the running statement of a connection when
+// performance_schema cannot be used
+const statement_is = `select DB AS db, INFO AS info, NULL AS sql_text
+                        from information_schema.PROCESSLIST
+                       where ID = ?`
+
+// Statement is the statement of a thread shown by the statement viewer
+type Statement struct {
+	ThreadId string
+	Db       string
+	Text     string
+	// Last is true when the thread runs nothing and Text is the last
+	// statement it ran, as much of it as performance_schema kept
+	Last bool
+}
+
+// GetStatement returns the statement of the thread, by its performance_schema
+// thread id when there is one, otherwise by its connection id.
+func GetStatement(mydb db.Querier, caps db.Capabilities, thread_id string, conn_id string) (Statement, error) {
+	var result *db.Result
+	var err error
+	id := thread_id
+	switch {
+	case caps.PerformanceSchema && !caps.MariaDB && len(thread_id) > 0:
+		result, err = db.Query(mydb, statement_ps+` where t.THREAD_ID = ?`, thread_id)
+	case caps.PerformanceSchema && !caps.MariaDB && len(conn_id) > 0:
+		result, err = db.Query(mydb, statement_ps+` where t.PROCESSLIST_ID = ?`, conn_id)
+		id = conn_id
+	default:
+		// information_schema only knows the connection ids
+		if len(conn_id) == 0 {
+			conn_id = thread_id
+		}
+		result, err = db.Query(mydb, statement_is, conn_id)
+		id = conn_id
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(result.Rows) < 1 {
+		return Statement{}, errors.New("not found")
+	}
+	row := result.Rows[0]
+	stmt := Statement{ThreadId: id, Db: row.Get("db").String(), Text: row.Get("info").String()}
+	if len(stmt.Text) == 0 {
+		stmt.Text = row.Get("sql_text").String()
+		stmt.Last = len(stmt.Text) > 0
+	}
+	return stmt, nil
+}
+
+// statement_colors are the colors of the kinds of tokens of a formatted
+// statement
+var statement_colors = map[int][]cell.Option{
+	sql_keyword: {cell.FgColor(cell.ColorNumber(31)), cell.Bold()},
+	sql_string:  {cell.FgColor(cell.ColorNumber(2))},
+	sql_number:  {cell.FgColor(cell.ColorNumber(172))},
+	sql_comment: {cell.FgColor(cell.ColorNumber(8)), cell.Italic()},
+}
+
+// DisplayStatement shows the statement, pretty-printed when formatted is
+// true, in a window scrolled with the arrow keys and the mouse
+func DisplayStatement(c *container.Container, stmt Statement, formatted bool) error {
+	statement_window, err := text.New(text.WrapAtWords())
+	if err != nil {
+		return err
+	}
+	c.Update("dyn_top_container", container.SplitHorizontal(container.Top(
+		container.Border(linestyle.Light),
+		container.ID("top_container"),
+	),
+		container.Bottom(
+			container.Border(linestyle.Light),
+			container.ID("main_container"),
+			container.PlaceWidget(statement_window),
+			container.FocusedColor(cell.ColorNumber(15)),
+		), container.SplitFixed(0)))
+	c.Update("bottom_container", container.Clear())
+	c.Update("main_container", container.Focused())
+	view := "original"
+	if formatted {
+		view = "formatted"
+	}
+	c.Update("main_container", container.BorderTitle(fmt.Sprintf(
+		"Statement (<-- <Backspace> to return  -  <f> to show the %s text  -  <c> to copy it)", view)))
+
+	label := text.WriteCellOpts(cell.FgColor(cell.ColorNumber(31)))
+	statement_window.Write("\n Thread: ", label)
+	statement_window.Write(stmt.ThreadId)
+	if len(stmt.Db) > 0 {
+		statement_window.Write("   Db: ", label)
+		statement_window.Write(stmt.Db)
+	}
+	statement_window.Write("   Length: ", label)
+	statement_window.Write(fmt.Sprintf("%d\n", len(stmt.Text)))
+	if stmt.Last {
+		statement_window.Write(" The thread runs no statement, this is the last one it ran, it can be truncated to performance_schema_max_sql_text_length.\n",
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
+	}
+	statement_window.Write("\n")
+	if len(stmt.Text) == 0 {
+		statement_window.Write(" The thread runs no statement.", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
+		return nil
+	}
+	if !formatted {
+		statement_window.Write(stmt.Text + "\n")
+		return nil
+	}
+	for _, line := range FormatSQL(stmt.Text) {
+		for _, segment := range line {
+			if opts, ok := statement_colors[segment.kind]; ok {
+				statement_window.Write(segment.text, text.WriteCellOpts(opts...))
+			} else {
+				statement_window.Write(segment.text)
+			}
+		}
+		statement_window.Write("\n")
+	}
+	return nil
+}
+
+// ClipboardTerminal is the terminal of termdash writing the OSC 52 escape
+// sequences which put a text in the clipboard, which also works through ssh
+// when the terminal allows it. tcell owns the output, the sequences are
+// written right after a frame so that they never cut one.
+type ClipboardTerminal struct {
+	terminalapi.Terminal
+	out     io.Writer
+	mutex   sync.Mutex
+	pending []string
+}
+
+// NewClipboardTerminal wraps t, out being where t writes the frames
+func NewClipboardTerminal(t terminalapi.Terminal, out io.Writer) *ClipboardTerminal {
+	return &ClipboardTerminal{Terminal: t, out: out}
+}
+
+// Flush draws the frame then writes the pending sequences
+func (t *ClipboardTerminal) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err := t.Terminal.Flush(); err != nil {
+		return err
+	}
+	for _, sequence := range t.pending {
+		if _, err := io.WriteString(t.out, sequence); err != nil {
+			t.pending = nil
+			return err
+		}
+	}
+	t.pending = nil
+	return nil
+}
+
+// CopyStatement puts the text in the clipboard at the next frame, termdash
+// draws one after each key
+func (t *ClipboardTerminal) CopyStatement(text string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pending = append(t.pending, fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text))))
+}
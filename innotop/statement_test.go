@@ -0,0 +1,37 @@
+package innotop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mum4k/termdash/terminal/terminalapi"
+)
+
+// frameTerminal writes a frame to the output shared with the sequences
+type frameTerminal struct {
+	terminalapi.Terminal
+	out *bytes.Buffer
+}
+
+func (t frameTerminal) Flush() error {
+	t.out.WriteString("<frame>")
+	return nil
+}
+
+func TestClipboardTerminal(t *testing.T) {
+	var out bytes.Buffer
+	clipboard := NewClipboardTerminal(frameTerminal{out: &out}, &out)
+	clipboard.CopyStatement("select 1")
+	if out.Len() > 0 {
+		t.Errorf("the sequence was written before the frame: %q", out.String())
+	}
+	if err := clipboard.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := clipboard.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<frame>\x1b]52;c;c2VsZWN0IDE=\x07<frame>"; out.String() != want {
+		t.Errorf("output is %q, want %q", out.String(), want)
+	}
+}
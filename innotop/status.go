@@ -1,9 +1,7 @@
 package innotop
 
 import (
-	"database/sql"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/lefred/innotopgo/db"
@@ -12,71 +10,71 @@ import (
 	"github.com/mum4k/termdash/widgets/text"
 )
 
-func GetStatus(mydb *sql.DB) ([]string, [][]string, error) {
-	stmt := `select variable_name, variable_value from performance_schema.global_status
+// global_status_query adds the statement counters to the global status
+const global_status_query = `select variable_name, variable_value from performance_schema.global_status
 	         union
 			 select event_name, count_star
 			 from performance_schema.events_statements_summary_global_by_event_name`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
-	if err != nil {
-		return nil, nil, err
+
+// GetStatus reads the global status, from performance_schema when the server
+// has it.
+func GetStatus(mydb db.Querier, caps db.Capabilities) (*db.Result, error) {
+	if caps.MariaDB || !caps.PerformanceSchema {
+		return db.Query(mydb, `SHOW GLOBAL STATUS`)
 	}
-	return cols, data, err
+	return db.Query(mydb, global_status_query)
 }
 
-func GetComStmt(mydb *sql.DB) ([]string, [][]string, error) {
+func GetComStmt(mydb db.Querier) (*db.Result, error) {
 	stmt := `SHOW GLOBAL STATUS LIKE 'Com_%'`
-	rows, err := db.Query(mydb, stmt)
-	if err != nil {
-		return nil, nil, err
-	}
-	cols, data, err := db.GetData(rows)
-	if err != nil {
-		return nil, nil, err
-	}
+	return db.Query(mydb, stmt)
+}
 
-	return cols, data, err
+// statusCounter returns a counter of the status as an int, 0 when missing
+func statusCounter(status map[string]db.Value, name string) int {
+	value, _ := status[name].Int64()
+	return int(value)
 }
 
-func DisplayStatus(mydb *sql.DB, top_window *text.Text, tlg *barchart.BarChart,
-	trg *sparkline.SparkLine, prev_status map[string]string, old_values []int) (map[string]string, []int, error) {
+func DisplayStatus(mydb db.Querier, caps db.Capabilities, top_window *text.Text, tlg *barchart.BarChart,
+	trg *sparkline.SparkLine, prev_status map[string]db.Value, old_values []int) (map[string]db.Value, []int, error) {
 	var line string
 	var real_qps int
-	_, data, err := GetStatus(mydb)
+	result, err := GetStatus(mydb, caps)
 	if err != nil {
 		return nil, nil, err
 	}
-	var status = make(map[string]string)
-	for _, row := range data {
-		status[row[0]] = row[1]
+	var status = make(map[string]db.Value)
+	for _, row := range result.Rows {
+		status[row.Values[0].String()] = row.Values[1]
 	}
-	_, data, err = GetComStmt(mydb)
+	result, err = GetComStmt(mydb)
 	if err != nil {
 		return nil, nil, err
 	}
-	var comstmt = make(map[string]string)
-	for _, row := range data {
-		comstmt[row[0]] = row[1]
+	var comstmt = make(map[string]db.Value)
+	for _, row := range result.Rows {
+		comstmt[row.Values[0].String()] = row.Values[1]
 	}
 
-	uptime_sec, _ := strconv.Atoi(status["Uptime"])
-	queries, _ := strconv.Atoi(status["Queries"])
+	uptime_sec := statusCounter(status, "Uptime")
+	queries := statusCounter(status, "Queries")
+	avg_qps := 0
+	if uptime_sec > 0 {
+		avg_qps = queries / uptime_sec
+	}
 	var values []int
 	if prev_status != nil {
-		prev_uptime_sec, _ := strconv.Atoi(prev_status["Uptime"])
-		prev_queries, _ := strconv.Atoi(prev_status["Queries"])
-		com_select, _ := strconv.Atoi(comstmt["Com_select"])
-		com_insert, _ := strconv.Atoi(comstmt["Com_insert"])
-		com_update, _ := strconv.Atoi(comstmt["Com_update"])
-		com_delete, _ := strconv.Atoi(comstmt["Com_delete"])
-		prev_com_select, _ := strconv.Atoi(prev_status["Com_select"])
-		prev_com_insert, _ := strconv.Atoi(prev_status["Com_insert"])
-		prev_com_update, _ := strconv.Atoi(prev_status["Com_update"])
-		prev_com_delete, _ := strconv.Atoi(prev_status["Com_delete"])
+		prev_uptime_sec := statusCounter(prev_status, "Uptime")
+		prev_queries := statusCounter(prev_status, "Queries")
+		com_select := statusCounter(comstmt, "Com_select")
+		com_insert := statusCounter(comstmt, "Com_insert")
+		com_update := statusCounter(comstmt, "Com_update")
+		com_delete := statusCounter(comstmt, "Com_delete")
+		prev_com_select := statusCounter(prev_status, "Com_select")
+		prev_com_insert := statusCounter(prev_status, "Com_insert")
+		prev_com_update := statusCounter(prev_status, "Com_update")
+		prev_com_delete := statusCounter(prev_status, "Com_delete")
 		max_value := 10
 
 		if (uptime_sec - prev_uptime_sec) < 1 {
@@ -105,7 +103,7 @@ func DisplayStatus(mydb *sql.DB, top_window *text.Text, tlg *barchart.BarChart,
 		line = fmt.Sprintf("Threads: %v/%v (run/con)", status["Threads_running"], status["Threads_connected"])
 		top_window.Write(line)
 		top_window.Write("\n")
-		line = fmt.Sprintf("    QPS: %-10v", (queries / uptime_sec))
+		line = fmt.Sprintf("    QPS: %-10v", avg_qps)
 		top_window.Write(line)
 		line = fmt.Sprintf("real QPS: %v", real_qps)
 		top_window.Write(line)
@@ -119,10 +117,10 @@ func DisplayStatus(mydb *sql.DB, top_window *text.Text, tlg *barchart.BarChart,
 		line = fmt.Sprintf("Threads: %v/%v (run/con)", status["Threads_running"], status["Threads_connected"])
 		top_window.Write(line)
 		top_window.Write("\n")
-		line = fmt.Sprintf("    QPS: %-10v", (queries / uptime_sec))
+		line = fmt.Sprintf("    QPS: %-10v", avg_qps)
 		top_window.Write(line)
 	}
-	combined_status := map[string]string{}
+	combined_status := map[string]db.Value{}
 	for k, v := range status {
 		combined_status[k] = v
 	}
@@ -0,0 +1,139 @@
+package innotop
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/widgets/barchart"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// setStatus answers the status statements of the server with the counters
+func setStatus(fake *db.Fake, caps db.Capabilities, uptime, queries, com_select, com_insert, com_update, com_delete int) {
+	status_stmt := global_status_query
+	if caps.MariaDB || !caps.PerformanceSchema {
+		status_stmt = "SHOW GLOBAL STATUS"
+	}
+	fake.Set(status_stmt, []string{"Variable_name", "Value"},
+		[]interface{}{"Uptime", uptime},
+		[]interface{}{"Queries", queries},
+		[]interface{}{"Threads_running", 2},
+		[]interface{}{"Threads_connected", 12},
+	)
+	fake.Set("SHOW GLOBAL STATUS LIKE 'Com_%'", []string{"Variable_name", "Value"},
+		[]interface{}{"Com_select", com_select},
+		[]interface{}{"Com_insert", com_insert},
+		[]interface{}{"Com_update", com_update},
+		[]interface{}{"Com_delete", com_delete},
+	)
+}
+
+func TestDisplayStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		caps db.Capabilities
+	}{
+		{name: "8.0", caps: newCapabilities("8.0.36", true, true)},
+		{name: "5.7 without performance_schema", caps: newCapabilities("5.7.44", false, false)},
+		{name: "MariaDB", caps: newCapabilities("10.6.12-MariaDB", true, true)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			top_window, err := text.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tlg, err := barchart.New(barchart.Labels([]string{"Sel", "Ins", "Upd", "Del"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			trg, err := newQPSGraph()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fake := db.NewFake()
+			setStatus(fake, test.caps, 1000, 50000, 400, 100, 50, 10)
+			status, values, err := DisplayStatus(fake, test.caps, top_window, tlg, trg, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values != nil {
+				t.Errorf("first refresh values are %v, want none", values)
+			}
+			if uptime := statusCounter(status, "Uptime"); uptime != 1000 {
+				t.Errorf("Uptime is %d, want 1000", uptime)
+			}
+			if com_select := statusCounter(status, "Com_select"); com_select != 400 {
+				t.Errorf("Com_select is %d, want 400", com_select)
+			}
+
+			// the values are the statements run since the previous refresh
+			setStatus(fake, test.caps, 1010, 51000, 450, 120, 55, 10)
+			status, values, err = DisplayStatus(fake, test.caps, top_window, tlg, trg, status, values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []int{50, 20, 5, 0}; !reflect.DeepEqual(values, want) {
+				t.Errorf("values are %v, want %v", values, want)
+			}
+
+			// no value when the refreshes are less than a second apart
+			_, values, err = DisplayStatus(fake, test.caps, top_window, tlg, trg, status, values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values != nil {
+				t.Errorf("values within the same second are %v, want none", values)
+			}
+		})
+	}
+}
+
+func TestInnoDBDeltas(t *testing.T) {
+	read_innodb_status := func(fake *db.Fake) map[string]string {
+		_, data, err := GetInnoDBStatus(fake)
+		if err != nil {
+			t.Fatal(err)
+		}
+		innodb_status := make(map[string]string)
+		for _, row := range data {
+			innodb_status[row[0]] = row[1]
+		}
+		return innodb_status
+	}
+	cols := []string{"variable_name", "variable_value"}
+	fake := db.NewFake()
+	fake.Set(innodb_status_query, cols,
+		[]interface{}{"Uptime", 100},
+		[]interface{}{"Innodb_buffer_pool_read_requests", 5000},
+		[]interface{}{"Innodb_buffer_pool_bytes_dirty", 8192},
+	)
+	prev := read_innodb_status(fake)
+	fake.Set(innodb_status_query, cols,
+		[]interface{}{"Uptime", 105},
+		[]interface{}{"Innodb_buffer_pool_read_requests", 6000},
+		[]interface{}{"Innodb_buffer_pool_bytes_dirty", 4096},
+	)
+	actual := read_innodb_status(fake)
+
+	tests := []struct {
+		name     string
+		prev     map[string]string
+		variable string
+		negative bool
+		want     int
+	}{
+		{name: "first refresh", variable: "Innodb_buffer_pool_read_requests", want: 6000},
+		{name: "per second", prev: prev, variable: "Innodb_buffer_pool_read_requests", want: 200},
+		{name: "decrease", prev: prev, variable: "Innodb_buffer_pool_bytes_dirty", want: 0},
+		{name: "negative decrease", prev: prev, variable: "Innodb_buffer_pool_bytes_dirty", negative: true, want: -819},
+		{name: "missing", prev: prev, variable: "Innodb_data_pending_reads", want: 0},
+	}
+	for _, test := range tests {
+		if value := GetValue(test.prev, actual, test.variable, test.negative); value != test.want {
+			t.Errorf("%s: GetValue(%s) = %d, want %d", test.name, test.variable, value, test.want)
+		}
+	}
+}
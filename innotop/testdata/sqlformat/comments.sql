@@ -0,0 +1,2 @@
+/* report */ select count(*) -- all rows
+from t where name = 'select from where' # trailing
@@ -0,0 +1 @@
+delete from sessions where last_seen < now() - interval 1 day order by last_seen limit 1000
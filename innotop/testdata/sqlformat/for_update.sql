@@ -0,0 +1 @@
+select * from t for update
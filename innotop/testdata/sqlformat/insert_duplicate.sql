@@ -0,0 +1 @@
+insert into t(a, b) values (1, 2), (3, 4) on duplicate key update a = values(a)
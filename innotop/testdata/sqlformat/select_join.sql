@@ -0,0 +1 @@
+select o.id, c.name, sum(l.amount) as total from orders o left join customers c on c.id = o.customer_id and c.active = 1 join lines l using (order_id) where o.created between '2024-01-01' and '2024-02-01' and o.status in ('new', 'paid', 'shipped', 'returned', 'cancelled', 'refunded', 'lost', 'on hold', 'pending') group by o.id, c.name having total > 100 order by total desc limit 10
@@ -0,0 +1 @@
+UPDATE orders SET status = 'late' WHERE id IN (SELECT order_id FROM shipments WHERE shipped IS NULL AND due < now())
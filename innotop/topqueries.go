@@ -0,0 +1,376 @@
+package innotop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/terminal/tcell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// top_query_counters are the counters of the digests whose deltas are
+// displayed, in the order of <, >
+var top_query_counters = []sortColumn{
+	{header: "Exec", column: "exec_count", numeric: true},
+	{header: "Latency", column: "latency", numeric: true},
+	{header: "Rows Exam", column: "rows_examined", numeric: true},
+	{header: "Rows Sent", column: "rows_sent", numeric: true},
+	{header: "Tmp Tables", column: "tmp_tables", numeric: true},
+	{header: "Tmp Disk", column: "tmp_disk_tables", numeric: true},
+	{header: "No Index", column: "no_index_used", numeric: true},
+}
+
+// top_queries_format is the layout of the lines of the Top Queries screen
+const top_queries_format = "%10v %12v %12v %12v %12v %10v %10v  %-16v %-v\n"
+
+const top_queries_counters = `SCHEMA_NAME AS db, DIGEST AS digest, COUNT_STAR AS exec_count, SUM_TIMER_WAIT AS latency,
+                              SUM_ROWS_EXAMINED AS rows_examined, SUM_ROWS_SENT AS rows_sent,
+                              SUM_CREATED_TMP_TABLES AS tmp_tables, SUM_CREATED_TMP_DISK_TABLES AS tmp_disk_tables,
+                              SUM_NO_INDEX_USED AS no_index_used`
+
+// GetTopQueries reads the counters of the statement digests. The baseline
+// reads all of them without their text, then only the digests run during
+// the last window are read, with their text and sample query.
+func GetTopQueries(mydb db.Querier, caps db.Capabilities, window time.Duration, baseline bool) (*db.Result, error) {
+	if baseline {
+		return db.Query(mydb, `select `+top_queries_counters+`
+                                 from performance_schema.events_statements_summary_by_digest`)
+	}
+	sample := "NULL"
+	if caps.QuerySampleText() {
+		sample = "QUERY_SAMPLE_TEXT"
+	}
+	stmt := `select ` + top_queries_counters + `, DIGEST_TEXT AS digest_text, ` + sample + ` AS sample
+               from performance_schema.events_statements_summary_by_digest
+              where LAST_SEEN >= now(6) - interval ? microsecond`
+	return db.Query(mydb, stmt, window.Microseconds())
+}
+
+// QuerySample is a query of a digest, run with the default schema Db
+type QuerySample struct {
+	Db   string
+	Text string
+}
+
+// TopQueries is the state of the Top Queries screen, it is kept while the
+// processlist is displayed so that the sort and the selection stay.
+type TopQueries struct {
+	Sort ProcesslistSort
+
+	mutex  sync.Mutex
+	server *Server
+	// prev are the counters of each digest at the last refresh, by schema
+	// and digest
+	prev map[string][]float64
+	// refreshed is the time of the last refresh, a baseline is read again
+	// after a long pause
+	refreshed time.Time
+	// rows are the deltas of the digests run during the last interval
+	rows     *db.Result
+	cursor   int
+	offset   int
+	selected string
+}
+
+func NewTopQueries() *TopQueries {
+	return &TopQueries{Sort: ProcesslistSort{columns: top_query_counters}}
+}
+
+func topQueryKey(row db.Row) string {
+	return row.Get("db").String() + "." + row.Get("digest").String()
+}
+
+// Update reads the counters of the server and computes the deltas since the
+// previous refresh, the first refresh on a server only reads a baseline.
+func (top *TopQueries) Update(srv *Server, interval time.Duration) error {
+	top.mutex.Lock()
+	baseline := srv != top.server || time.Since(top.refreshed) > 2*interval
+	top.mutex.Unlock()
+	// the digests run since the previous refresh, with some margin
+	result, err := GetTopQueries(srv.DB, srv.Capabilities, 2*interval, baseline)
+	if err != nil {
+		return err
+	}
+	top.mutex.Lock()
+	defer top.mutex.Unlock()
+	top.refreshed = time.Now()
+	if baseline {
+		top.server = srv
+		top.prev = map[string][]float64{}
+		top.rows = nil
+	}
+	var rows [][]interface{}
+	for _, row := range result.Rows {
+		key := topQueryKey(row)
+		counters := make([]float64, len(top_query_counters))
+		for i, counter := range top_query_counters {
+			counters[i], _ = row.Get(counter.column).Float64()
+		}
+		prev, seen := top.prev[key]
+		top.prev[key] = counters
+		if baseline {
+			continue
+		}
+		// the digests first run since the baseline, or run again after a
+		// truncation of the table, count from 0
+		if seen && prev[0] <= counters[0] {
+			for i := range counters {
+				counters[i] -= prev[i]
+			}
+		}
+		if counters[0] <= 0 {
+			continue
+		}
+		values := []interface{}{key, row.Get("db").String(), row.Get("digest_text").String(), row.Get("sample").String()}
+		for _, delta := range counters {
+			values = append(values, delta)
+		}
+		rows = append(rows, values)
+	}
+	if baseline {
+		return nil
+	}
+	columns := []string{"key", "db", "digest_text", "sample"}
+	for _, counter := range top_query_counters {
+		columns = append(columns, counter.column)
+	}
+	top.rows = db.NewResult(columns, rows)
+	return nil
+}
+
+// Forget drops the counters, the next refresh reads a baseline
+func (top *TopQueries) Forget() {
+	top.mutex.Lock()
+	defer top.mutex.Unlock()
+	top.server = nil
+	top.rows = nil
+}
+
+// MoveCursor moves the selection by lines
+func (top *TopQueries) MoveCursor(lines int) {
+	top.mutex.Lock()
+	defer top.mutex.Unlock()
+	top.cursor += lines
+	top.clamp(0)
+}
+
+// clamp keeps the cursor on a digest and the page of height rows around
+// the cursor, the caller holds the mutex
+func (top *TopQueries) clamp(height int) {
+	count := 0
+	if top.rows != nil {
+		count = len(top.rows.Rows)
+	}
+	if top.cursor >= count {
+		top.cursor = count - 1
+	}
+	if top.cursor < 0 {
+		top.cursor = 0
+	}
+	top.selected = ""
+	if count > 0 {
+		top.selected = top.rows.Rows[top.cursor].Get("key").String()
+	}
+	if top.cursor < top.offset {
+		top.offset = top.cursor
+	}
+	if height > 0 && top.cursor >= top.offset+height {
+		top.offset = top.cursor - height + 1
+	}
+}
+
+// Selected returns the sample query of the selected digest
+func (top *TopQueries) Selected() (QuerySample, bool) {
+	top.mutex.Lock()
+	defer top.mutex.Unlock()
+	if top.rows == nil || top.cursor >= len(top.rows.Rows) {
+		return QuerySample{}, false
+	}
+	row := top.rows.Rows[top.cursor]
+	return QuerySample{Db: row.Get("db").String(), Text: row.Get("sample").String()}, true
+}
+
+// Display writes the digests sorted on the chosen delta, the selected one
+// being highlighted
+func (top *TopQueries) Display(window *ProcesslistWindow, interval time.Duration) {
+	top.mutex.Lock()
+	defer top.mutex.Unlock()
+	window.Reset()
+	if top.rows == nil {
+		window.Write(fmt.Sprintf("\n\n... please wait, the deltas need two refreshes (%v)...", interval),
+			text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
+		return
+	}
+	top.Sort.Apply(top.rows)
+	for i, row := range top.rows.Rows {
+		if row.Get("key").String() == top.selected {
+			top.cursor = i
+		}
+	}
+	// the header takes the first line
+	height := window.Height() - 1
+	top.clamp(height)
+	order := &top.Sort
+	var headers []interface{}
+	for _, counter := range top_query_counters {
+		headers = append(headers, order.Header(counter.header))
+	}
+	headers = append(headers, "Db", "Query")
+	window.Write(fmt.Sprintf(top_queries_format, headers...), text.WriteCellOpts(cell.Bold()))
+	end := len(top.rows.Rows)
+	if height > 0 && top.offset+height < end {
+		end = top.offset + height
+	}
+	for i := top.offset; i < end; i++ {
+		row := top.rows.Rows[i]
+		var values []interface{}
+		for _, counter := range top_query_counters {
+			delta, _ := row.Get(counter.column).Float64()
+			if counter.column == "latency" {
+				values = append(values, FormatPico(delta))
+			} else {
+				values = append(values, fmt.Sprintf("%.0f", delta))
+			}
+		}
+		values = append(values, ChunkString(row.Get("db").String(), 16), row.Get("digest_text").String())
+		line := fmt.Sprintf(top_queries_format, values...)
+		if i == end-1 {
+			// a last empty line would not fit in the window
+			line = strings.TrimSuffix(line, "\n")
+		}
+		if i == top.cursor {
+			window.Write(line, text.WriteCellOpts(cell.Inverse()))
+			continue
+		}
+		window.Write(line)
+	}
+}
+
+// DisplayTopQueries shows the statement digests run during each interval.
+// It returns the key leaving the screen, <e> asking for the EXPLAIN of the
+// sample query returned.
+func DisplayTopQueries(servers *Servers, top *TopQueries, c *container.Container, t *tcell.Terminal, interval time.Duration) (keyboard.Key, QuerySample, error) {
+	ctxtop, cancel := context.WithCancel(context.Background())
+	k := keyboard.KeyBackspace2
+	var sample QuerySample
+	main_window, err := text.New()
+	if err != nil {
+		cancel()
+		return k, sample, err
+	}
+	list_window := NewProcesslistWindow(main_window)
+	list_window.Write("\n\n... please wait...", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Italic()))
+
+	c.Update("dyn_top_container", container.SplitHorizontal(container.Top(
+		container.Border(linestyle.Light),
+		container.ID("top_container"),
+	),
+		container.Bottom(
+			container.Border(linestyle.Light),
+			container.ID("main_container"),
+			container.PlaceWidget(list_window),
+			container.FocusedColor(cell.ColorNumber(15)),
+		), container.SplitFixed(0)))
+	c.Update("bottom_container", container.Clear())
+	c.Update("main_container", container.Focused())
+	c.Update("main_container", container.BorderTitle(fmt.Sprintf(
+		"Top Queries, deltas of the last %v (<-- <Backspace> to return to Processlist - <e> EXPLAIN the sample - <<> <>> <r> sort)", interval)))
+
+	var drawing sync.Mutex
+	redraw := func() {
+		drawing.Lock()
+		defer drawing.Unlock()
+		top.Display(list_window, interval)
+	}
+	refresh := servers.Refresh(func(srv *Server) error {
+		if len(srv.Unavailable(ScreenTopQueries)) > 0 {
+			// the quitter goes back to the processlist
+			return nil
+		}
+		if err := top.Update(srv, interval); err != nil {
+			return err
+		}
+		redraw()
+		return nil
+	})
+	// a failed refresh, like a query timeout, is shown in place of the
+	// digests and tried again at the next interval, the lost connections
+	// are handled by servers.Refresh
+	refreshOrShow := func() error {
+		if err := refresh(); err != nil {
+			drawing.Lock()
+			defer drawing.Unlock()
+			list_window.Reset()
+			list_window.Write(fmt.Sprintf("\n\nthe refresh failed, retrying in %v: %v", interval, err),
+				text.WriteCellOpts(cell.FgColor(cell.ColorNumber(172)), cell.Bold()))
+		}
+		return nil
+	}
+	refreshOrShow()
+	go periodic(ctxtop, interval, refreshOrShow)
+
+	quitter := func(k2 *terminalapi.Keyboard) {
+		switch {
+		case k2.Key == keyboard.KeyEsc || k2.Key == keyboard.KeyCtrlC || k2.Key == keyboard.KeyBackspace2:
+			k = k2.Key
+			cancel()
+		case servers.HandleKey(k2):
+			top.Forget()
+			if len(servers.Current().Unavailable(ScreenTopQueries)) > 0 {
+				// the processlist tells why
+				k = keyboard.KeyBackspace2
+				cancel()
+			}
+		case k2.Key == 'e':
+			if selected, ok := top.Selected(); ok {
+				sample = selected
+				k = k2.Key
+				cancel()
+			}
+		case k2.Key == keyboard.KeyArrowUp || k2.Key == keyboard.KeyArrowDown:
+			if k2.Key == keyboard.KeyArrowUp {
+				top.MoveCursor(-1)
+			} else {
+				top.MoveCursor(1)
+			}
+			redraw()
+		case k2.Key == keyboard.KeyPgUp || k2.Key == keyboard.KeyPgDn:
+			page := list_window.Height() - 1
+			if page < 1 {
+				page = 1
+			}
+			if k2.Key == keyboard.KeyPgUp {
+				top.MoveCursor(-page)
+			} else {
+				top.MoveCursor(page)
+			}
+			redraw()
+		case k2.Key == '<' || k2.Key == '>':
+			if k2.Key == '<' {
+				top.Sort.Move(-1)
+			} else {
+				top.Sort.Move(1)
+			}
+			redraw()
+		case k2.Key == 'r':
+			top.Sort.Reverse()
+			redraw()
+		}
+	}
+	if err := termdash.Run(ctxtop, t, c, termdash.KeyboardSubscriber(quitter), termdash.RedrawInterval(redrawInterval)); err != nil {
+		cancel()
+		t.Close()
+		return k, sample, err
+	}
+	return k, sample, nil
+}
@@ -0,0 +1,69 @@
+package innotop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lefred/innotopgo/db"
+)
+
+func TestTopQueriesUpdate(t *testing.T) {
+	caps := newCapabilities("8.0.36", true, true)
+	baseline_stmt := `select ` + top_queries_counters + ` from performance_schema.events_statements_summary_by_digest`
+	window_stmt := `select ` + top_queries_counters + `, DIGEST_TEXT AS digest_text, QUERY_SAMPLE_TEXT AS sample
+               from performance_schema.events_statements_summary_by_digest
+              where LAST_SEEN >= now(6) - interval ? microsecond`
+	counters := []string{"db", "digest", "exec_count", "latency", "rows_examined", "rows_sent", "tmp_tables", "tmp_disk_tables", "no_index_used"}
+	fake := db.NewFake()
+	fake.Set(baseline_stmt, counters,
+		[]interface{}{"shop", "4f2a", 100, 5000, 1000, 100, 0, 0, 0},
+		[]interface{}{"shop", "9b1c", 10, 800, 20, 10, 0, 0, 10},
+	)
+	fake.Set(window_stmt, append(counters, "digest_text", "sample"),
+		[]interface{}{"shop", "4f2a", 130, 6500, 1300, 130, 0, 0, 0, "SELECT * FROM `orders` WHERE `id` = ?", "select * from orders where id = 7"},
+		// first run since the baseline
+		[]interface{}{"shop", "77aa", 2, 90, 4, 2, 1, 0, 0, "SELECT SLEEP (?)", "select sleep(1)"},
+	)
+	srv := &Server{Name: "db1", DB: fake, Capabilities: caps}
+	servers := NewServers([]*Server{srv})
+	top := NewTopQueries()
+	refresh := servers.Refresh(func(srv *Server) error {
+		return top.Update(srv, time.Second)
+	})
+
+	if err := refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if top.rows != nil {
+		t.Errorf("the baseline has deltas: %v", top.rows.Strings())
+	}
+	if err := refresh(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]float64{"shop.4f2a": {30, 1500, 300, 30}, "shop.77aa": {2, 90, 4, 2}}
+	check := func() {
+		t.Helper()
+		if top.rows == nil || len(top.rows.Rows) != len(want) {
+			t.Fatalf("deltas are %v, want %v", top.rows, want)
+		}
+		for _, row := range top.rows.Rows {
+			key := row.Get("key").String()
+			for i, column := range []string{"exec_count", "latency", "rows_examined", "rows_sent"} {
+				if delta, _ := row.Get(column).Float64(); delta != want[key][i] {
+					t.Errorf("%s of %s is %v, want %v", column, key, delta, want[key][i])
+				}
+			}
+		}
+	}
+	check()
+
+	// a failed query is returned to the screen, which keeps its deltas
+	fake.Add(db.Fixture{Statement: window_stmt, Error: "Query execution was interrupted, maximum statement execution time exceeded"})
+	if err := refresh(); err == nil {
+		t.Errorf("the failed refresh returned no error")
+	}
+	check()
+	if lost, _, _ := srv.reconnect.Status(); lost {
+		t.Errorf("a failed query was taken for a lost connection")
+	}
+}
@@ -0,0 +1,54 @@
+package innotop
+
+import (
+	"fmt"
+
+	"github.com/lefred/innotopgo/db"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// DisplayTrace shows the last statements run by innotopgo over the current
+// screen, the returned window is refreshed with WriteTrace.
+func DisplayTrace(c *container.Container) (*text.Text, error) {
+	trace_window, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+	c.Update("dyn_top_container", container.SplitHorizontal(container.Top(
+		container.Border(linestyle.Light),
+		container.ID("top_container"),
+	),
+		container.Bottom(
+			container.Border(linestyle.Light),
+			container.ID("main_container"),
+			container.PlaceWidget(trace_window),
+			container.FocusedColor(cell.ColorNumber(15)),
+		), container.SplitFixed(0)))
+	c.Update("bottom_container", container.Clear())
+	c.Update("main_container", container.Focused())
+	c.Update("main_container", container.BorderTitle("Statements run by innotopgo, most recent first (<-- <Backspace> to return to Processlist)"))
+	WriteTrace(trace_window)
+	return trace_window, nil
+}
+
+func WriteTrace(trace_window *text.Text) {
+	trace_window.Reset()
+	trace_window.Write(fmt.Sprintf("%-12v %-16v %10v %6v  %v\n", "Start", "Screen", "Latency", "Rows", "Statement"),
+		text.WriteCellOpts(cell.FgColor(cell.ColorNumber(6)), cell.Bold()))
+	for _, trace := range db.LastTraces() {
+		rows := "-"
+		if trace.Rows >= 0 {
+			rows = fmt.Sprintf("%d", trace.Rows)
+		}
+		latency := fmt.Sprintf("%.2f ms", float64(trace.Duration.Microseconds())/1000)
+		trace_window.Write(fmt.Sprintf("%-12v %-16v %10v %6v  %v", trace.Start.Format("15:04:05.000"), trace.Screen,
+			latency, rows, trace.Statement()))
+		if trace.Err != nil {
+			trace_window.Write("  "+trace.Err.Error(), text.WriteCellOpts(cell.FgColor(cell.ColorRed)))
+		}
+		trace_window.Write("\n")
+	}
+}
@@ -1,36 +1,386 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lefred/innotopgo/db"
 	"github.com/lefred/innotopgo/innotop"
 	"github.com/lefred/innotopgo/parse"
 )
 
+const usage_header = `Usage: innotopgo [command] [options] [mysql://<username>:<password>@<host>:3306]
+       innotopgo [command] [options] [mysql://<username>@/path/to/mysqld.sock]
+       innotopgo [command] [options] <uri> <uri>...
+       innotopgo [command] [options] --servers <name>,<name>...
+
+Commands:
+  top          run the interactive dashboard (default)
+  kill-daemon  log or kill the threads matching the rules of --rules, without
+               killing anything until --dry-run=false is given
+  version      print the version and exit
+  help         print this help and exit
+
+Options:
+`
+
+var commands = map[string]func(args []string) error{
+	"top":         runTop,
+	"kill-daemon": runKillDaemon,
+	"version":     runVersion,
+	"help":        runHelp,
+}
+
 func main() {
-	var displaytype = "simple"
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: innotopgo mysql://<username>:<password>@<host>:3306")
-		os.Exit(1)
+	args := os.Args[1:]
+	command := "top"
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			command = args[0]
+			args = args[1:]
+		}
 	}
-	if len(os.Args) < 3 {
-		displaytype = "normal"
-	} else {
-		displaytype = os.Args[2]
+	err := commands[command](args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
 	}
-	uri, err := parse.Parse(os.Args[1])
 	if err != nil {
 		innotop.ExitWithError(err)
 	}
-	mydb, err := db.Connect(uri)
+}
+
+type topOptions struct {
+	uris     []string
+	config   string
+	servers  string
+	flags    parse.Config
+	sources  parse.Sources
+	interval time.Duration
+	mode     string
+	screen   string
+	trace    string
+	record   string
+	replay   string
+	version  bool
+	rules    string
+	dry_run  bool
+	kill_log string
+	// interval_set is true when --interval was given
+	interval_set bool
+}
+
+func newFlagSet(opts *topOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("innotopgo", flag.ContinueOnError)
+	fs.StringVar(&opts.flags.Host, "host", "", "MySQL server host (default localhost)")
+	fs.StringVar(&opts.flags.Port, "port", "", "MySQL server port (default "+parse.DefaultPort+")")
+	fs.StringVar(&opts.flags.User, "user", "", "MySQL user")
+	fs.StringVar(&opts.flags.Socket, "socket", "", "path to the MySQL unix socket (takes precedence over host and port)")
+	fs.StringVar(&opts.flags.SSLMode, "ssl-mode", "", "security state of the connection: DISABLED, PREFERRED, REQUIRED, VERIFY_CA or VERIFY_IDENTITY")
+	fs.StringVar(&opts.flags.SSLCA, "ssl-ca", "", "file containing the trusted SSL Certificate Authorities")
+	fs.StringVar(&opts.flags.SSLCert, "ssl-cert", "", "file containing the client SSL certificate")
+	fs.StringVar(&opts.flags.SSLKey, "ssl-key", "", "file containing the client SSL key")
+	fs.StringVar(&opts.flags.SSLServerName, "ssl-server-name", "", "server name expected in the certificate with VERIFY_IDENTITY (default the host)")
+	fs.StringVar(&opts.flags.SSH, "ssh", "", "reach the MySQL server through a SSH tunnel to this [user@]bastion[:port]")
+	fs.StringVar(&opts.flags.SSHKey, "ssh-key", "", "private key used to log in the bastion (default the SSH agent and ~/.ssh/id_*)")
+	fs.StringVar(&opts.flags.SSHKnownHosts, "ssh-known-hosts", "", "known_hosts file used to check the bastion host key (default ~/.ssh/known_hosts)")
+	fs.StringVar(&opts.sources.DefaultsFile, "defaults-file", "", "read the options only from this file")
+	fs.StringVar(&opts.sources.DefaultsGroupSuffix, "defaults-group-suffix", "", "also read the option groups with this suffix")
+	fs.StringVar(&opts.sources.LoginPath, "login-path", "", "read the options from this login path of ~/.mylogin.cnf")
+	fs.BoolVar(&opts.sources.AskPassword, "ask-password", false, "ask for the password")
+	fs.BoolVar(&opts.sources.AskPassword, "p", false, "shorthand for --ask-password")
+	fs.StringVar(&opts.config, "config", "", "innotopgo configuration file (default ~/.innotopgo.cnf)")
+	fs.StringVar(&opts.servers, "servers", "", "comma separated names of the servers of the configuration file to monitor, or 'all'")
+	fs.DurationVar(&opts.interval, "interval", 1*time.Second, "refresh interval of the screens")
+	fs.StringVar(&opts.mode, "mode", innotop.ModeNormal, "display mode: normal (dashboard) or simple (print the processlist once)")
+	fs.StringVar(&opts.screen, "screen", innotop.ScreenProcesslist, "screen to start on: "+innotop.ScreenNames())
+	fs.StringVar(&opts.trace, "trace-file", "", "append every statement run by innotopgo to this file")
+	fs.StringVar(&opts.record, "record", "", "record the results of the statements in this file to replay them later")
+	fs.StringVar(&opts.replay, "replay", "", "play back a recording made with --record instead of connecting to servers")
+	fs.BoolVar(&opts.version, "version", false, "print the version and exit")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage_header)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// newKillFlagSet adds the options of the kill daemon to the ones of top
+func newKillFlagSet(opts *topOptions) *flag.FlagSet {
+	fs := newFlagSet(opts)
+	fs.StringVar(&opts.rules, "rules", "", "kill-daemon: file of the rules matching the threads to log or to kill")
+	fs.BoolVar(&opts.dry_run, "dry-run", true, "kill-daemon: only log the threads the rules would kill")
+	fs.StringVar(&opts.kill_log, "kill-log", "", "kill-daemon: append the threads matched to this file (default the standard output)")
+	return fs
+}
+
+// parseArgs parses the flags and the optional URIs. The URIs can be given
+// before, between or after the flags.
+func parseArgs(args []string, newFlags func(opts *topOptions) *flag.FlagSet) (*topOptions, error) {
+	opts := &topOptions{}
+	fs := newFlags(opts)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	for fs.NArg() > 0 {
+		opts.uris = append(opts.uris, fs.Arg(0))
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if opts.version {
+		return opts, nil
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "interval" {
+			opts.interval_set = true
+		}
+	})
+	if len(opts.replay) > 0 {
+		if len(opts.record) > 0 {
+			return nil, errors.New("--record and --replay cannot be used together")
+		}
+		if len(opts.uris) > 0 || len(opts.servers) > 0 {
+			return nil, errors.New("--replay plays a recording back, no server can be given")
+		}
+	}
+	if len(opts.uris) > 0 && len(opts.servers) > 0 {
+		return nil, errors.New("servers cannot be given both as URIs and with --servers")
+	}
+	if len(opts.uris) > 1 || len(opts.servers) > 0 {
+		if len(opts.flags.Host) > 0 || len(opts.flags.Port) > 0 || len(opts.flags.Socket) > 0 {
+			return nil, errors.New("--host, --port and --socket cannot be used with several servers")
+		}
+	}
+	if len(opts.flags.Port) > 0 {
+		port, err := strconv.Atoi(opts.flags.Port)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port '%s'", opts.flags.Port)
+		}
+	}
+	if opts.interval <= 0 {
+		return nil, fmt.Errorf("invalid interval '%v', it must be positive", opts.interval)
+	}
+	if opts.mode != innotop.ModeNormal && opts.mode != innotop.ModeSimple {
+		return nil, fmt.Errorf("invalid mode '%s', use %s or %s", opts.mode, innotop.ModeNormal, innotop.ModeSimple)
+	}
+	if !innotop.ValidScreen(opts.screen) {
+		return nil, fmt.Errorf("invalid screen '%s', use one of: %s", opts.screen, innotop.ScreenNames())
+	}
+	return opts, nil
+}
+
+func runTop(args []string) error {
+	opts, err := parseArgs(args, newFlagSet)
 	if err != nil {
-		innotop.ExitWithError(err)
+		return err
+	}
+	if opts.version {
+		return runVersion(nil)
+	}
+	if len(opts.trace) > 0 {
+		if err := db.OpenTrace(opts.trace); err != nil {
+			return err
+		}
+		defer db.CloseTrace()
 	}
-	defer mydb.Close()
-	err = innotop.Processlist(mydb, displaytype)
+	config_file := opts.config
+	if len(config_file) == 0 {
+		config_file = parse.DefaultConfigFile()
+	}
+	config, err := parse.ReadConfigFile(config_file, len(opts.config) > 0)
 	if err != nil {
-		innotop.ExitWithError(err)
+		return err
+	}
+	var servers *innotop.Servers
+	if len(opts.replay) > 0 {
+		servers, err = replayServers(opts)
+	} else {
+		servers, err = connectServers(opts, config, config_file)
+	}
+	if err != nil {
+		return err
+	}
+	defer closeServers(servers)
+	if len(opts.record) > 0 {
+		if err := recordServers(opts, servers); err != nil {
+			return err
+		}
+		defer db.CloseRecord()
 	}
+	return innotop.Processlist(servers, innotop.Options{
+		Mode:     opts.mode,
+		Screen:   opts.screen,
+		Interval: opts.interval,
+		Filters:  config.Filters(),
+	})
+}
+
+// runKillDaemon evaluates the kill rules against the processlist of the
+// servers until it is interrupted
+func runKillDaemon(args []string) error {
+	opts, err := parseArgs(args, newKillFlagSet)
+	if err != nil {
+		return err
+	}
+	if opts.version {
+		return runVersion(nil)
+	}
+	if len(opts.rules) == 0 {
+		return errors.New("kill-daemon needs a rules file, give it with --rules")
+	}
+	if len(opts.replay) > 0 || len(opts.record) > 0 {
+		return errors.New("kill-daemon works on live servers, --record and --replay cannot be used")
+	}
+	rules_file, err := parse.ReadConfigFile(opts.rules, true)
+	if err != nil {
+		return err
+	}
+	rules, err := innotop.NewKillRules(rules_file.Rules())
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.rules, err)
+	}
+	kill_log := os.Stdout
+	if len(opts.kill_log) > 0 {
+		kill_log, err = os.OpenFile(opts.kill_log, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		defer kill_log.Close()
+	}
+	if len(opts.trace) > 0 {
+		if err := db.OpenTrace(opts.trace); err != nil {
+			return err
+		}
+		defer db.CloseTrace()
+	}
+	config_file := opts.config
+	if len(config_file) == 0 {
+		config_file = parse.DefaultConfigFile()
+	}
+	config, err := parse.ReadConfigFile(config_file, len(opts.config) > 0)
+	if err != nil {
+		return err
+	}
+	servers, err := connectServers(opts, config, config_file)
+	if err != nil {
+		return err
+	}
+	defer closeServers(servers)
+	return innotop.KillDaemon(servers, rules, innotop.KillOptions{
+		Interval: opts.interval,
+		DryRun:   opts.dry_run,
+		Log:      kill_log,
+	})
+}
+
+// connectServers opens a connection pool to each server given as URI or
+// picked from the configuration file with --servers.
+func connectServers(opts *topOptions, config parse.OptionGroups, config_file string) (*innotop.Servers, error) {
+	var names []string
+	var cfgs []parse.Config
+	switch {
+	case len(opts.servers) > 0:
+		names = strings.Split(opts.servers, ",")
+		if opts.servers == "all" {
+			names = config.Servers()
+			if len(names) == 0 {
+				return nil, fmt.Errorf("no server defined in %s", config_file)
+			}
+		}
+		for _, name := range names {
+			server, ok := config.Server(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("server '%s' not found in %s", name, config_file)
+			}
+			cfgs = append(cfgs, server)
+		}
+	case len(opts.uris) > 0:
+		for _, uri := range opts.uris {
+			server, err := parse.ParseURI(uri)
+			if err != nil {
+				return nil, err
+			}
+			cfgs = append(cfgs, server)
+		}
+	default:
+		cfgs = append(cfgs, parse.Config{})
+	}
+
+	var list []*innotop.Server
+	for i, server := range cfgs {
+		cfg, err := parse.Resolve(server, opts.flags, opts.sources)
+		if err != nil {
+			closeServers(innotop.NewServers(list))
+			return nil, err
+		}
+		name := cfg.Address()
+		if len(names) > 0 {
+			name = strings.TrimSpace(names[i])
+		}
+		mydb, err := db.Connect(name, cfg)
+		if err != nil {
+			closeServers(innotop.NewServers(list))
+			return nil, err
+		}
+		list = append(list, &innotop.Server{Name: name, DB: mydb, Socket: cfg.Socket})
+	}
+	return innotop.NewServers(list), nil
+}
+
+// replayServers plays back the servers of a recording, at the interval of
+// the recording unless --interval is given
+func replayServers(opts *topOptions) (*innotop.Servers, error) {
+	replay, err := db.OpenReplay(opts.replay)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.interval_set && replay.Interval > 0 {
+		opts.interval = replay.Interval
+	}
+	var list []*innotop.Server
+	for _, name := range replay.Servers {
+		list = append(list, &innotop.Server{Name: name, DB: replay.Querier(name), Replay: replay})
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no server in the recording %s", opts.replay)
+	}
+	return innotop.NewServers(list), nil
+}
+
+// recordServers saves the results of the statements run on the servers
+func recordServers(opts *topOptions, servers *innotop.Servers) error {
+	var names []string
+	for _, srv := range servers.List() {
+		names = append(names, srv.Name)
+	}
+	if err := db.OpenRecord(opts.record, opts.interval, names); err != nil {
+		return err
+	}
+	for _, srv := range servers.List() {
+		srv.DB = db.Record(srv.DB, srv.Name)
+	}
+	return nil
+}
+
+func closeServers(servers *innotop.Servers) {
+	for _, srv := range servers.List() {
+		srv.DB.Close()
+	}
+}
+
+func runVersion(args []string) error {
+	fmt.Printf("innotopgo %s\n", innotop.Version())
+	return nil
+}
+
+func runHelp(args []string) error {
+	fs := newKillFlagSet(&topOptions{})
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+	return nil
 }
@@ -0,0 +1,94 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The innotopgo configuration file uses the option file syntax, each named
+// server being a group like:
+//
+//	[server db1]
+//	host = db1.example.com
+//	user = monitor
+const server_group_prefix = "server "
+
+// filters_group holds the named processlist filters, like:
+//
+//	[filters]
+//	locks = cmd!=Sleep stmt~/FOR UPDATE/
+const filters_group = "filters"
+
+const rule_group_prefix = "rule "
+
+func DefaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".innotopgo.cnf")
+}
+
+// ReadConfigFile reads the innotopgo configuration file, a missing file
+// is only an error when required is set.
+func ReadConfigFile(file string, required bool) (OptionGroups, error) {
+	if len(file) == 0 {
+		return OptionGroups{}, nil
+	}
+	if _, err := os.Stat(file); err != nil {
+		if os.IsNotExist(err) && !required {
+			return OptionGroups{}, nil
+		}
+		return nil, err
+	}
+	return ReadOptionFiles([]string{file})
+}
+
+// Servers returns the names of the servers defined in the configuration
+// file, sorted by name.
+func (opts OptionGroups) Servers() []string {
+	var names []string
+	for group := range opts {
+		if strings.HasPrefix(group, server_group_prefix) {
+			names = append(names, strings.TrimSpace(strings.TrimPrefix(group, server_group_prefix)))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (opts OptionGroups) Server(name string) (Config, bool) {
+	group := server_group_prefix + strings.ToLower(name)
+	if _, ok := opts[group]; !ok {
+		return Config{}, false
+	}
+	return opts.Config(group), true
+}
+
+// Filters returns the named processlist filters of the configuration file
+func (opts OptionGroups) Filters() map[string]string {
+	filters := map[string]string{}
+	for name, expr := range opts[filters_group] {
+		filters[name] = expr
+	}
+	return filters
+}
+
+// Rules returns the groups of a kill rules file, each rule being a group
+// like:
+//
+//	[rule reports]
+//	user = report
+//	min-time = 60s
+//	action = kill-query
+func (opts OptionGroups) Rules() map[string]map[string]string {
+	rules := map[string]map[string]string{}
+	for group, options := range opts {
+		if strings.HasPrefix(group, rule_group_prefix) {
+			rules[strings.TrimSpace(strings.TrimPrefix(group, rule_group_prefix))] = options
+		}
+	}
+	return rules
+}
@@ -0,0 +1,61 @@
+package parse
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "innotopgo.cnf")
+	writeFile(t, file, `[filters]
+selects = "cmd!=Sleep stmt~/\bSELECT\s/"
+locks = stmt~/\bFOR\s+UPDATE\b/ # the locking reads
+[server db1]
+password = two\swords
+`)
+	opts, err := ReadConfigFile(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the regular expressions keep their escape sequences
+	want := map[string]string{
+		"selects": `cmd!=Sleep stmt~/\bSELECT\s/`,
+		"locks":   `stmt~/\bFOR\s+UPDATE\b/`,
+	}
+	filters := opts.Filters()
+	if len(filters) != len(want) {
+		t.Errorf("filters are %v, want %v", filters, want)
+	}
+	for name, expr := range want {
+		if filters[name] != expr {
+			t.Errorf("filter %s is %q, want %q", name, filters[name], expr)
+		}
+	}
+	// the options of the servers do not
+	if cfg, _ := opts.Server("db1"); cfg.Password != "two words" {
+		t.Errorf("password is %q, want %q", cfg.Password, "two words")
+	}
+}
+
+func TestRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.cnf")
+	writeFile(t, file, `[rule sleepers]
+statement = \bSELECT\s+SLEEP\b
+action = kill-query
+[rule locks]
+match = "stmt~/\bFOR\s+UPDATE\b/ time>5s" # the long locking reads
+action = log
+`)
+	opts, err := ReadConfigFile(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"sleepers": {"statement": `\bSELECT\s+SLEEP\b`, "action": "kill-query"},
+		"locks":    {"match": `stmt~/\bFOR\s+UPDATE\b/ time>5s`, "action": "log"},
+	}
+	if rules := opts.Rules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules are %q, want %q", rules, want)
+	}
+}
@@ -2,27 +2,272 @@ package parse
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"os"
+	"os/user"
 	"strings"
 )
 
-func Parse(uri_to_parse string) (string, error) {
+const DefaultPort = "3306"
 
-	uri, err := url.Parse(uri_to_parse)
+const (
+	SSLModeDisabled       = "DISABLED"
+	SSLModePreferred      = "PREFERRED"
+	SSLModeRequired       = "REQUIRED"
+	SSLModeVerifyCA       = "VERIFY_CA"
+	SSLModeVerifyIdentity = "VERIFY_IDENTITY"
+)
+
+// Config holds everything needed to reach a MySQL server, see Resolve for
+// where the values come from.
+type Config struct {
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Socket   string
+
+	SSLMode       string
+	SSLCA         string
+	SSLCert       string
+	SSLKey        string
+	SSLServerName string
+
+	// SSH is the [user@]host[:port] of the bastion to tunnel through
+	SSH           string
+	SSHKey        string
+	SSHKnownHosts string
+}
+
+// Sources tells Parse where to find the settings missing from the URI and
+// the flags.
+type Sources struct {
+	// DefaultsFile replaces the default option files when set
+	DefaultsFile        string
+	DefaultsGroupSuffix string
+	// LoginPath is the group to read from the mysql_config_editor file
+	LoginPath   string
+	AskPassword bool
+}
+
+// Parse returns the connection settings of the server given by the URI,
+// see Resolve for the other sources of settings.
+func Parse(uri_to_parse string, flags Config, sources Sources) (Config, error) {
+	uri_cfg, err := ParseURI(uri_to_parse)
 	if err != nil {
-		return "", err
+		return uri_cfg, err
 	}
+	return Resolve(uri_cfg, flags, sources)
+}
+
+func ParseURI(uri_to_parse string) (Config, error) {
+	var uri_cfg Config
 
-	if uri.Scheme == "" || !strings.HasPrefix(uri_to_parse, "mysql://") {
+	if len(uri_to_parse) == 0 {
+		return uri_cfg, nil
+	}
+	if !strings.HasPrefix(uri_to_parse, "mysql://") {
 		uri_to_parse = fmt.Sprintf("mysql://%s", uri_to_parse)
-		uri, err = url.Parse(uri_to_parse)
+	}
+	uri, err := url.Parse(uri_to_parse)
+	if err != nil {
+		return uri_cfg, err
+	}
+	if uri.User != nil {
+		uri_cfg.User = uri.User.Username()
+		uri_cfg.Password, _ = uri.User.Password()
+	}
+	uri_cfg.Host = uri.Hostname()
+	uri_cfg.Port = uri.Port()
+	if len(uri_cfg.Host) == 0 && len(uri.Path) > 1 {
+		// mysql://user@/path/to/socket
+		uri_cfg.Socket = uri.Path
+	}
+
+	for key, values := range uri.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "ssl-mode":
+			uri_cfg.SSLMode = value
+		case "ssl-ca":
+			uri_cfg.SSLCA = value
+		case "ssl-cert":
+			uri_cfg.SSLCert = value
+		case "ssl-key":
+			uri_cfg.SSLKey = value
+		case "ssl-server-name":
+			uri_cfg.SSLServerName = value
+		case "ssh":
+			uri_cfg.SSH = value
+		case "ssh-key":
+			uri_cfg.SSHKey = value
+		case "ssh-known-hosts":
+			uri_cfg.SSHKnownHosts = value
+		default:
+			return uri_cfg, fmt.Errorf("unknown URI parameter '%s'", key)
+		}
+	}
+	return uri_cfg, nil
+}
+
+// Resolve completes the settings of a server. They are read, each source
+// overriding the previous ones, from MYSQL_PWD, the option files, the login
+// path file, the server settings and the flags. The password is finally
+// asked on the terminal when sources.AskPassword is set.
+func Resolve(server Config, flags Config, sources Sources) (Config, error) {
+	var cfg Config
+
+	cfg.merge(Config{Password: os.Getenv("MYSQL_PWD")})
+
+	files := DefaultOptionFiles()
+	if len(sources.DefaultsFile) > 0 {
+		files = []string{sources.DefaultsFile}
+		if _, err := os.Stat(sources.DefaultsFile); err != nil {
+			return cfg, err
+		}
+	}
+	option_groups, err := ReadOptionFiles(files)
+	if err != nil {
+		return cfg, err
+	}
+	groups := []string{"client", "innotopgo"}
+	if len(sources.DefaultsGroupSuffix) > 0 {
+		groups = append(groups, "client"+sources.DefaultsGroupSuffix, "innotopgo"+sources.DefaultsGroupSuffix)
+	}
+	cfg.merge(option_groups.Config(groups...))
+
+	login_groups, err := ReadLoginPathFile(LoginPathFile())
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+	if len(sources.LoginPath) > 0 && sources.LoginPath != DefaultLoginPath {
+		if _, ok := login_groups[sources.LoginPath]; !ok {
+			return cfg, fmt.Errorf("login path '%s' not found in %s", sources.LoginPath, LoginPathFile())
+		}
+		cfg.merge(login_groups.Config(DefaultLoginPath, sources.LoginPath))
+	} else {
+		cfg.merge(login_groups.Config(DefaultLoginPath))
+	}
+
+	cfg.merge(server)
+	cfg.merge(flags)
+
+	if len(cfg.Host) == 0 {
+		cfg.Host = "localhost"
+	}
+	if len(cfg.Port) == 0 {
+		cfg.Port = DefaultPort
+	}
+
+	if len(cfg.User) == 0 {
+		// like the mysql client, default to the login name
+		if current, err := user.Current(); err == nil {
+			cfg.User = current.Username
+		}
+	}
+	if sources.AskPassword {
+		cfg.Password, err = ReadPassword(fmt.Sprintf("Enter password for %s@%s: ", cfg.User, cfg.Address()))
 		if err != nil {
-			return "", err
+			return cfg, err
 		}
 	}
-	pwd, _ := uri.User.Password()
 
-	mysql_uri := fmt.Sprintf("%s:%s@tcp(%s)/", uri.User.Username(), pwd, uri.Host)
+	if err := cfg.checkSSL(); err != nil {
+		return cfg, err
+	}
+	if len(cfg.SSH) == 0 && (len(cfg.SSHKey) > 0 || len(cfg.SSHKnownHosts) > 0) {
+		return cfg, fmt.Errorf("ssh-key and ssh-known-hosts require ssh")
+	}
+
+	return cfg, nil
+}
 
-	return mysql_uri, nil
+// merge overrides the values of cfg with the non empty values of other.
+func (cfg *Config) merge(other Config) {
+	if len(other.User) > 0 {
+		cfg.User = other.User
+	}
+	if len(other.Password) > 0 {
+		cfg.Password = other.Password
+	}
+	if len(other.Host) > 0 {
+		cfg.Host = other.Host
+		if len(other.Socket) == 0 {
+			// a host given by a later source wins over an earlier socket
+			cfg.Socket = ""
+		}
+	}
+	if len(other.Port) > 0 {
+		cfg.Port = other.Port
+	}
+	if len(other.Socket) > 0 {
+		cfg.Socket = other.Socket
+	}
+	if len(other.SSLMode) > 0 {
+		cfg.SSLMode = other.SSLMode
+	}
+	if len(other.SSLCA) > 0 {
+		cfg.SSLCA = other.SSLCA
+	}
+	if len(other.SSLCert) > 0 {
+		cfg.SSLCert = other.SSLCert
+	}
+	if len(other.SSLKey) > 0 {
+		cfg.SSLKey = other.SSLKey
+	}
+	if len(other.SSLServerName) > 0 {
+		cfg.SSLServerName = other.SSLServerName
+	}
+	if len(other.SSH) > 0 {
+		cfg.SSH = other.SSH
+	}
+	if len(other.SSHKey) > 0 {
+		cfg.SSHKey = other.SSHKey
+	}
+	if len(other.SSHKnownHosts) > 0 {
+		cfg.SSHKnownHosts = other.SSHKnownHosts
+	}
+}
+
+// checkSSL validates the SSL settings and picks the mode like the mysql
+// client does when none is given: VERIFY_CA when a CA is provided,
+// REQUIRED when a client certificate is provided, PREFERRED otherwise.
+func (cfg *Config) checkSSL() error {
+	cfg.SSLMode = strings.ToUpper(cfg.SSLMode)
+	if len(cfg.SSLMode) == 0 {
+		if len(cfg.SSLCA) > 0 {
+			cfg.SSLMode = SSLModeVerifyCA
+		} else if len(cfg.SSLCert) > 0 {
+			cfg.SSLMode = SSLModeRequired
+		} else {
+			cfg.SSLMode = SSLModePreferred
+		}
+	}
+	switch cfg.SSLMode {
+	case SSLModeDisabled, SSLModePreferred:
+		if len(cfg.SSLCert) > 0 {
+			return fmt.Errorf("ssl-cert cannot be used with ssl-mode %s", cfg.SSLMode)
+		}
+	case SSLModeRequired, SSLModeVerifyIdentity:
+	case SSLModeVerifyCA:
+		if len(cfg.SSLCA) == 0 {
+			return fmt.Errorf("ssl-mode %s requires ssl-ca", cfg.SSLMode)
+		}
+	default:
+		return fmt.Errorf("invalid ssl-mode '%s', use one of: %s, %s, %s, %s, %s", cfg.SSLMode,
+			SSLModeDisabled, SSLModePreferred, SSLModeRequired, SSLModeVerifyCA, SSLModeVerifyIdentity)
+	}
+	if (len(cfg.SSLCert) > 0) != (len(cfg.SSLKey) > 0) {
+		return fmt.Errorf("ssl-cert and ssl-key must be provided together")
+	}
+	return nil
+}
+
+// Address is the socket or the host:port of the server
+func (cfg Config) Address() string {
+	if len(cfg.Socket) > 0 {
+		return cfg.Socket
+	}
+	return net.JoinHostPort(cfg.Host, cfg.Port)
 }
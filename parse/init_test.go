@@ -0,0 +1,76 @@
+package parse
+
+import "testing"
+
+func TestCheckSSL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		mode string
+		err  bool
+	}{
+		{name: "preferred by default", cfg: Config{}, mode: SSLModePreferred},
+		{name: "verify_ca with a CA", cfg: Config{SSLCA: "ca.pem"}, mode: SSLModeVerifyCA},
+		{name: "required with a certificate", cfg: Config{SSLCert: "cert.pem", SSLKey: "key.pem"}, mode: SSLModeRequired},
+		{name: "case insensitive", cfg: Config{SSLMode: "verify_identity"}, mode: SSLModeVerifyIdentity},
+		{name: "verify_identity with a CA", cfg: Config{SSLMode: SSLModeVerifyIdentity, SSLCA: "ca.pem"}, mode: SSLModeVerifyIdentity},
+		{name: "verify_ca without CA", cfg: Config{SSLMode: SSLModeVerifyCA}, err: true},
+		{name: "certificate without encryption", cfg: Config{SSLMode: SSLModeDisabled, SSLCert: "cert.pem", SSLKey: "key.pem"}, err: true},
+		{name: "certificate without key", cfg: Config{SSLCert: "cert.pem"}, err: true},
+		{name: "key without certificate", cfg: Config{SSLMode: SSLModeRequired, SSLKey: "key.pem"}, err: true},
+		{name: "unknown mode", cfg: Config{SSLMode: "ALWAYS"}, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := test.cfg
+			err := cfg.checkSSL()
+			if test.err {
+				if err == nil {
+					t.Errorf("checkSSL accepted %+v", test.cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.SSLMode != test.mode {
+				t.Errorf("ssl-mode is %s, want %s", cfg.SSLMode, test.mode)
+			}
+		})
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want Config
+		err  bool
+	}{
+		{uri: "", want: Config{}},
+		{uri: "monitor:s3cret@db1:3307", want: Config{User: "monitor", Password: "s3cret", Host: "db1", Port: "3307"}},
+		{uri: "mysql://monitor@db1", want: Config{User: "monitor", Host: "db1"}},
+		{uri: "monitor@/var/run/mysqld/mysqld.sock", want: Config{User: "monitor", Socket: "/var/run/mysqld/mysqld.sock"}},
+		{uri: "monitor@db1?ssl-mode=VERIFY_CA&ssl-ca=/etc/ca.pem",
+			want: Config{User: "monitor", Host: "db1", SSLMode: "VERIFY_CA", SSLCA: "/etc/ca.pem"}},
+		{uri: "monitor@10.0.0.12?ssh=admin@bastion:2222&ssh-key=/home/me/.ssh/id_ed25519&ssh-known-hosts=/etc/ssh/known_hosts",
+			want: Config{User: "monitor", Host: "10.0.0.12", SSH: "admin@bastion:2222",
+				SSHKey: "/home/me/.ssh/id_ed25519", SSHKnownHosts: "/etc/ssh/known_hosts"}},
+		{uri: "monitor@db1?compress=1", err: true},
+	}
+	for _, test := range tests {
+		cfg, err := ParseURI(test.uri)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseURI(%s) accepted the URI", test.uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseURI(%s): %v", test.uri, err)
+			continue
+		}
+		if cfg != test.want {
+			t.Errorf("ParseURI(%s) = %+v, want %+v", test.uri, cfg, test.want)
+		}
+	}
+}
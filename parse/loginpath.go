@@ -0,0 +1,75 @@
+package parse
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const DefaultLoginPath = "client"
+
+// LoginPathFile returns the file written by mysql_config_editor
+func LoginPathFile() string {
+	if file := os.Getenv("MYSQL_TEST_LOGIN_FILE"); len(file) > 0 {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mylogin.cnf")
+}
+
+// ReadLoginPathFile decrypts the obfuscated option file written by
+// mysql_config_editor. The file starts with 4 unused bytes and the 20 bytes
+// of the key, followed by lines encrypted with AES-128-ECB, each one
+// prefixed by its length on 4 bytes.
+func ReadLoginPathFile(file string) (OptionGroups, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < 24 {
+		return nil, errors.New(file + ": invalid login path file")
+	}
+	var key [aes.BlockSize]byte
+	for i, b := range content[4:24] {
+		key[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var plain bytes.Buffer
+	content = content[24:]
+	for len(content) >= 4 {
+		size := int(binary.LittleEndian.Uint32(content[:4]))
+		content = content[4:]
+		if size == 0 || size > len(content) || size%aes.BlockSize != 0 {
+			return nil, errors.New(file + ": invalid login path file")
+		}
+		line := make([]byte, size)
+		for i := 0; i < size; i += aes.BlockSize {
+			block.Decrypt(line[i:i+aes.BlockSize], content[i:i+aes.BlockSize])
+		}
+		// remove the PKCS#7 padding
+		if pad := int(line[size-1]); pad > 0 && pad <= aes.BlockSize && pad <= size {
+			line = line[:size-pad]
+		}
+		plain.Write(line)
+		content = content[size:]
+	}
+
+	opts := OptionGroups{}
+	// mysql_config_editor does not write !include directives, starting at
+	// the maximum depth refuses them
+	if err := opts.read(&plain, file, max_include_depth); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,96 @@
+package parse
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeLoginPathFile obfuscates the lines like mysql_config_editor: 4 unused
+// bytes, the 20 bytes of the key, then each line encrypted with AES-128-ECB
+// and prefixed by its length
+func writeLoginPathFile(t *testing.T, file string, lines []string) {
+	t.Helper()
+	raw_key := []byte("0123456789abcdefghij")
+	var key [aes.BlockSize]byte
+	for i, b := range raw_key {
+		key[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content bytes.Buffer
+	content.Write([]byte{0, 0, 0, 0})
+	content.Write(raw_key)
+	for _, line := range lines {
+		plain := []byte(line + "\n")
+		pad := aes.BlockSize - len(plain)%aes.BlockSize
+		plain = append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+		encrypted := make([]byte, len(plain))
+		for i := 0; i < len(plain); i += aes.BlockSize {
+			block.Encrypt(encrypted[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+		}
+		binary.Write(&content, binary.LittleEndian, uint32(len(encrypted)))
+		content.Write(encrypted)
+	}
+	if err := ioutil.WriteFile(file, content.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadLoginPathFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".mylogin.cnf")
+	writeLoginPathFile(t, file, []string{
+		"[client]",
+		`user = "app"`,
+		`password = "exactly16bytes!!"`,
+		"[prod]",
+		`user = "admin"`,
+		`host = "prod-db1"`,
+		`port = 3307`,
+	})
+	opts, err := ReadLoginPathFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := opts.Config(DefaultLoginPath, "prod")
+	if cfg.User != "admin" || cfg.Password != "exactly16bytes!!" || cfg.Host != "prod-db1" || cfg.Port != "3307" {
+		t.Errorf("config is %+v", cfg)
+	}
+
+	t.Setenv("MYSQL_PWD", "")
+	t.Setenv("MYSQL_TEST_LOGIN_FILE", file)
+	defaults := filepath.Join(t.TempDir(), "my.cnf")
+	writeFile(t, defaults, "[client]\nhost = db1\n")
+	cfg, err = Parse("", Config{}, Sources{DefaultsFile: defaults, LoginPath: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "admin" || cfg.Host != "prod-db1" {
+		t.Errorf("the login path prod gives %s@%s, want admin@prod-db1", cfg.User, cfg.Host)
+	}
+	if _, err := Parse("", Config{}, Sources{DefaultsFile: defaults, LoginPath: "staging"}); err == nil {
+		t.Errorf("an unknown login path is accepted")
+	}
+}
+
+func TestReadLoginPathFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"short":     []byte("abc"),
+		"truncated": append(make([]byte, 24), 32, 0, 0, 0, 1, 2, 3),
+		"unaligned": append(make([]byte, 24), 5, 0, 0, 0, 1, 2, 3, 4, 5),
+	} {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadLoginPathFile(file); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// max_include_depth protects against !include loops
+const max_include_depth = 10
+
+// OptionGroups is the content of option files: for each group, the options and
+// their values. Option names are stored with '-' as word separator.
+type OptionGroups map[string]map[string]string
+
+// DefaultOptionFiles returns the option files read by the MySQL client
+// programs, in the order they are read.
+func DefaultOptionFiles() []string {
+	files := []string{"/etc/my.cnf", "/etc/mysql/my.cnf"}
+	if mysql_home := os.Getenv("MYSQL_HOME"); len(mysql_home) > 0 {
+		files = append(files, filepath.Join(mysql_home, "my.cnf"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".my.cnf"))
+	}
+	return files
+}
+
+// ReadOptionFiles reads the option files in order, missing files are
+// ignored. Values read later override the ones read before.
+func ReadOptionFiles(files []string) (OptionGroups, error) {
+	opts := OptionGroups{}
+	for _, file := range files {
+		err := opts.readFile(file, 0)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func (opts OptionGroups) readFile(file string, depth int) error {
+	if depth > max_include_depth {
+		return fmt.Errorf("%s: too many nested !include", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return opts.read(f, file, depth)
+}
+
+func (opts OptionGroups) read(r io.Reader, file string, depth int) error {
+	group := ""
+	scanner := bufio.NewScanner(r)
+	line_nb := 0
+	for scanner.Scan() {
+		line_nb++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "!include") {
+			if err := opts.include(line, file, depth); err != nil {
+				return err
+			}
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("%s:%d: invalid group '%s'", file, line_nb, line)
+			}
+			group = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if len(group) == 0 {
+			return fmt.Errorf("%s:%d: option '%s' found before any group", file, line_nb, line)
+		}
+		name, value := line, ""
+		if i := strings.Index(line, "="); i >= 0 {
+			name = strings.TrimSpace(line[:i])
+			value = optionValue(line[i+1:])
+		}
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+		if opts[group] == nil {
+			opts[group] = map[string]string{}
+		}
+		opts[group][name] = value
+	}
+	return scanner.Err()
+}
+
+// include handles the !include and !includedir directives, relative paths
+// are resolved from the directory of the file containing the directive.
+func (opts OptionGroups) include(line string, file string, depth int) error {
+	directive := strings.Fields(line)[0]
+	path := strings.TrimSpace(strings.TrimPrefix(line, directive))
+	if len(path) > 0 && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+	switch directive {
+	case "!include":
+		if err := opts.readFile(path, depth+1); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		return nil
+	case "!includedir":
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".cnf") {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := opts.readFile(filepath.Join(path, name), depth+1); err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unknown directive '%s'", file, directive)
+}
+
+// optionValue removes the trailing comment and the quotes around a value.
+// The escape sequences are kept, the regular expressions of the filters and
+// the kill rules use the same ones: Config replaces them in the values of
+// the MySQL options.
+func optionValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+var unescaper = strings.NewReplacer(`\b`, "\b", `\t`, "\t", `\n`, "\n", `\r`, "\r", `\\`, `\`, `\s`, " ")
+
+func unescape(value string) string {
+	return unescaper.Replace(value)
+}
+
+// Config returns the connection settings found in the given groups, later
+// groups override earlier ones.
+func (opts OptionGroups) Config(groups ...string) Config {
+	var cfg Config
+	for _, group := range groups {
+		value := func(name string) string {
+			return unescape(opts[group][name])
+		}
+		cfg.merge(Config{
+			User:          value("user"),
+			Password:      value("password"),
+			Host:          value("host"),
+			Port:          value("port"),
+			Socket:        value("socket"),
+			SSLMode:       value("ssl-mode"),
+			SSLCA:         value("ssl-ca"),
+			SSLCert:       value("ssl-cert"),
+			SSLKey:        value("ssl-key"),
+			SSLServerName: value("ssl-server-name"),
+			SSH:           value("ssh"),
+			SSHKey:        value("ssh-key"),
+			SSHKnownHosts: value("ssh-known-hosts"),
+		})
+	}
+	return cfg
+}
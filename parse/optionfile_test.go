@@ -0,0 +1,161 @@
+package parse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, file string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOptionFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "my.cnf"), `# the main file
+[client]
+user = app
+password = "p#ss word"   # quoted, the comment is dropped
+host=db1.example.com # inline comment
+port = 3307
+ssl_mode = 'REQUIRED'
+
+!include extra/more.cnf
+!includedir conf.d
+`)
+	writeFile(t, filepath.Join(dir, "extra", "more.cnf"), `[client]
+socket = /var/run/mysqld/mysqld.sock
+[innotopgo]
+user = monitor
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "b.cnf"), "[client]\nport = 3309\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "a.cnf"), "[client]\nport = 3308\nhost = db2\n")
+	// only the .cnf files are read
+	writeFile(t, filepath.Join(dir, "conf.d", "c.txt"), "[client]\nport = 3310\n")
+
+	opts, err := ReadOptionFiles([]string{filepath.Join(dir, "missing.cnf"), filepath.Join(dir, "my.cnf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OptionGroups{
+		"client": {
+			"user": "app", "password": "p#ss word", "host": "db2", "port": "3309", "ssl-mode": "REQUIRED",
+			"socket": "/var/run/mysqld/mysqld.sock",
+		},
+		"innotopgo": {"user": "monitor"},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("options are %v, want %v", opts, want)
+	}
+	cfg := opts.Config("client", "innotopgo")
+	if cfg.User != "monitor" || cfg.Password != "p#ss word" || cfg.Port != "3309" || cfg.SSLMode != "REQUIRED" {
+		t.Errorf("config is %+v", cfg)
+	}
+}
+
+func TestOptionValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "secret", want: "secret"},
+		{value: "  secret  ", want: "secret"},
+		{value: "secret # comment", want: "secret"},
+		{value: "se#cret", want: "se#cret"},
+		{value: `"two words" # comment`, want: "two words"},
+		{value: `'a # b'`, want: "a # b"},
+		{value: `"unterminated`, want: `"unterminated`},
+		// the escape sequences are replaced by Config only
+		{value: `"stmt~/\bSELECT\s/"`, want: `stmt~/\bSELECT\s/`},
+	}
+	for _, test := range tests {
+		if value := optionValue(test.value); value != test.want {
+			t.Errorf("optionValue(%q) = %q, want %q", test.value, value, test.want)
+		}
+	}
+}
+
+func TestOptionEscapes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "my.cnf")
+	writeFile(t, file, "[client]\npassword = tab\\there\\\\now\\sand\n")
+	opts, err := ReadOptionFiles([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg := opts.Config("client"); cfg.Password != "tab\there\\now and" {
+		t.Errorf("password is %q", cfg.Password)
+	}
+}
+
+func TestReadOptionFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "option before any group", content: "user = app\n"},
+		{name: "unterminated group", content: "[client\n"},
+		{name: "missing include", content: "[client]\n!include missing.cnf\n"},
+		{name: "include loop", content: "[client]\n!include include loop.cnf\n"},
+	}
+	for _, test := range tests {
+		file := filepath.Join(dir, test.name+".cnf")
+		writeFile(t, file, test.content)
+		if _, err := ReadOptionFiles([]string{file}); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestParseGroupSuffix(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "my.cnf")
+	writeFile(t, file, `[client]
+user = app
+host = db1
+[client_prod]
+host = prod-db1
+[innotopgo]
+port = 3307
+[innotopgo_prod]
+user = monitor
+`)
+	t.Setenv("MYSQL_PWD", "")
+	t.Setenv("MYSQL_TEST_LOGIN_FILE", filepath.Join(dir, "missing.mylogin.cnf"))
+	tests := []struct {
+		suffix string
+		user   string
+		host   string
+	}{
+		{suffix: "", user: "app", host: "db1"},
+		{suffix: "_prod", user: "monitor", host: "prod-db1"},
+	}
+	for _, test := range tests {
+		cfg, err := Parse("", Config{}, Sources{DefaultsFile: file, DefaultsGroupSuffix: test.suffix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.User != test.user || cfg.Host != test.host || cfg.Port != "3307" {
+			t.Errorf("suffix %q: config is %s@%s:%s, want %s@%s:3307", test.suffix, cfg.User, cfg.Host, cfg.Port, test.user, test.host)
+		}
+	}
+	// the flags override the files
+	cfg, err := Parse("", Config{User: "root"}, Sources{DefaultsFile: file, DefaultsGroupSuffix: "_prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "root" {
+		t.Errorf("user is %s, want root", cfg.User)
+	}
+	if _, err := Parse("", Config{}, Sources{DefaultsFile: filepath.Join(dir, "missing.cnf")}); err == nil {
+		t.Errorf("a missing --defaults-file is accepted")
+	}
+}
@@ -0,0 +1,448 @@
+// Command sqlcheck fails when a statement given to a query function is built
+// with fmt.Sprintf or a concatenation from a value coming from outside: a
+// function parameter, a loop variable, a function result or a row of a
+// result. Such values must be passed as arguments of ? placeholders.
+//
+// Usage: go run ./tools/sqlcheck [dir...]
+//
+// go test runs it on the whole module, the test run fails on the statements
+// it reports.
+//
+// A statement returned by a function of the same package, called by its
+// name, is reported when that function builds it from its parameters or
+// other outside input. The check stops there: the statements built by
+// methods, closures or the functions of other packages, and the ones going
+// through struct fields, maps or channels, are not followed. These have to
+// be caught in review.
+//
+// A statement which has to be built from outside input, like the query text
+// given to EXPLAIN, is accepted when the line above carries a
+// "// sqlcheck:ignore <reason>" comment.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ignore_directive = "sqlcheck:ignore"
+
+// query_funcs are the functions and methods taking a statement
+var query_funcs = map[string]bool{
+	"Query":           true,
+	"QueryTimeout":    true,
+	"QueryContext":    true,
+	"QueryRow":        true,
+	"QueryRowContext": true,
+	"RunQuery":        true,
+	"Exec":            true,
+	"GetData":         true,
+	"ExecContext":     true,
+	"Prepare":         true,
+	"PrepareContext":  true,
+}
+
+func main() {
+	dirs := os.Args[1:]
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	var problems []string
+	for _, dir := range dirs {
+		found, err := checkTree(strings.TrimSuffix(dir, "/..."))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		problems = append(problems, found...)
+	}
+	sort.Strings(problems)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func checkTree(root string) ([]string, error) {
+	var problems []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+		found, err := checkDir(path)
+		problems = append(problems, found...)
+		return err
+	})
+	return problems, err
+}
+
+func checkDir(dir string) ([]string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, pkg := range pkgs {
+		globals := map[string]bool{}
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if gen, ok := decl.(*ast.GenDecl); ok && (gen.Tok == token.CONST || gen.Tok == token.VAR) {
+					for _, spec := range gen.Specs {
+						for _, name := range spec.(*ast.ValueSpec).Names {
+							globals[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+		// the functions returning a statement built from outside input
+		builders := map[string]bool{}
+		for _, file := range pkg.Files {
+			c := &checker{fset: fset, file: file, globals: globals, imports: imports(file)}
+			for _, decl := range file.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil && fn.Recv == nil && c.builds(fn) {
+					builders[fn.Name.Name] = true
+				}
+			}
+		}
+		for _, file := range pkg.Files {
+			c := &checker{fset: fset, file: file, globals: globals, imports: imports(file), builders: builders}
+			for _, decl := range file.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+					problems = append(problems, c.checkFunc(fn)...)
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+func imports(file *ast.File) map[string]bool {
+	names := map[string]bool{}
+	for _, spec := range file.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+type checker struct {
+	fset    *token.FileSet
+	file    *ast.File
+	globals map[string]bool
+	imports map[string]bool
+	// builders are the functions of the package returning a statement
+	// built from outside input
+	builders map[string]bool
+
+	// state of the function being checked, the names are not scoped
+	untrusted map[string]bool
+	assigned  map[string][]ast.Expr
+	visiting  map[string]bool
+	reported  map[token.Pos]bool
+	problems  []string
+}
+
+func (c *checker) checkFunc(fn *ast.FuncDecl) []string {
+	c.analyze(fn)
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if !query_funcs[funcName(call)] {
+			return true
+		}
+		// the arguments following the statement are its placeholder values
+		for _, arg := range call.Args {
+			if c.checkStatement(arg) {
+				break
+			}
+		}
+		return true
+	})
+	return c.problems
+}
+
+// builds tells if the function returns a string built from outside input
+func (c *checker) builds(fn *ast.FuncDecl) bool {
+	c.analyze(fn)
+	builds := false
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		if _, ok := node.(*ast.FuncLit); ok {
+			// the returns of a closure are not the ones of fn
+			return false
+		}
+		ret, ok := node.(*ast.ReturnStmt)
+		if !ok {
+			return true
+		}
+		for _, result := range ret.Results {
+			exprs := []ast.Expr{result}
+			if ident, ok := result.(*ast.Ident); ok {
+				exprs = c.assigned[ident.Name]
+			}
+			for _, e := range exprs {
+				if e != nil && c.built(e) && !c.trusted(e) && !c.ignored(e) {
+					builds = true
+				}
+			}
+		}
+		return true
+	})
+	return builds
+}
+
+// analyze records the parameters, the variables and their values of fn
+func (c *checker) analyze(fn *ast.FuncDecl) {
+	c.untrusted = map[string]bool{}
+	c.assigned = map[string][]ast.Expr{}
+	c.visiting = map[string]bool{}
+	c.reported = map[token.Pos]bool{}
+	c.problems = nil
+
+	ast.Inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncType:
+			if n.Params != nil {
+				for _, field := range n.Params.List {
+					for _, name := range field.Names {
+						c.untrusted[name.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			for _, expr := range []ast.Expr{n.Key, n.Value} {
+				if ident, ok := expr.(*ast.Ident); ok {
+					c.untrusted[ident.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if len(n.Lhs) == len(n.Rhs) {
+					c.assigned[ident.Name] = append(c.assigned[ident.Name], n.Rhs[i])
+				} else {
+					// values returned by a call
+					c.untrusted[ident.Name] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				c.assigned[name.Name] = append(c.assigned[name.Name], nil)
+				if i < len(n.Values) && len(n.Names) == len(n.Values) {
+					c.assigned[name.Name] = append(c.assigned[name.Name], n.Values[i])
+				} else if len(n.Values) > 0 {
+					c.untrusted[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+// checkStatement reports the built statements which are not trusted, a
+// variable is checked through all the values assigned to it. It returns
+// whether expr is a statement.
+func (c *checker) checkStatement(expr ast.Expr) bool {
+	exprs := []ast.Expr{expr}
+	if ident, ok := expr.(*ast.Ident); ok {
+		exprs = c.assigned[ident.Name]
+	}
+	statement := false
+	for _, e := range exprs {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			statement = true
+		}
+		if call, ok := e.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && c.builders[ident.Name] {
+				statement = true
+				if !c.ignored(e) && !c.reported[e.Pos()] {
+					c.reported[e.Pos()] = true
+					c.problems = append(c.problems, fmt.Sprintf("%s: statement built from outside input by %s, use ? placeholders",
+						c.fset.Position(e.Pos()), ident.Name))
+				}
+				continue
+			}
+		}
+		if e == nil || !c.built(e) {
+			continue
+		}
+		statement = true
+		if c.trusted(e) || c.ignored(e) || c.reported[e.Pos()] {
+			continue
+		}
+		c.reported[e.Pos()] = true
+		c.problems = append(c.problems, fmt.Sprintf("%s: statement built from outside input, use ? placeholders",
+			c.fset.Position(e.Pos())))
+	}
+	return statement
+}
+
+func (c *checker) built(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return c.built(e.X)
+	case *ast.BinaryExpr:
+		return e.Op == token.ADD
+	case *ast.CallExpr:
+		return isFunc(e, "fmt", "Sprintf")
+	}
+	return false
+}
+
+func (c *checker) trusted(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return c.trusted(e.X)
+	case *ast.BinaryExpr:
+		return e.Op == token.ADD && c.trusted(e.X) && c.trusted(e.Y)
+	case *ast.SliceExpr:
+		return c.trusted(e.X)
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok && c.imports[ident.Name] {
+			return true
+		}
+		return c.trusted(e.X)
+	case *ast.Ident:
+		return c.trustedIdent(e.Name)
+	case *ast.CallExpr:
+		if isFunc(e, "fmt", "Sprintf") {
+			return c.trustedSprintf(e)
+		}
+		if isFunc(e, "strings", "Repeat") {
+			return c.trusted(e.Args[0])
+		}
+	}
+	return false
+}
+
+func (c *checker) trustedIdent(name string) bool {
+	if c.untrusted[name] {
+		return false
+	}
+	values, ok := c.assigned[name]
+	if !ok {
+		return c.globals[name] || name == "true" || name == "false"
+	}
+	if c.visiting[name] {
+		return true
+	}
+	c.visiting[name] = true
+	defer delete(c.visiting, name)
+	for _, value := range values {
+		if value != nil && !c.trusted(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// trustedSprintf accepts the outside values printed as integers
+func (c *checker) trustedSprintf(call *ast.CallExpr) bool {
+	if len(call.Args) == 0 {
+		return false
+	}
+	var verbs []rune
+	if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		verbs = formatVerbs(lit.Value)
+	} else if !c.trusted(call.Args[0]) {
+		return false
+	}
+	for i, arg := range call.Args[1:] {
+		if c.trusted(arg) {
+			continue
+		}
+		if i >= len(verbs) || !strings.ContainsRune("dboxX", verbs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatVerbs returns the verbs of a format in the order of their arguments,
+// nothing when the arguments are indexed
+func formatVerbs(format string) []rune {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*", format[i]) >= 0 {
+			i++
+		}
+		if i >= len(format) || format[i] == '[' {
+			return nil
+		}
+		if format[i] != '%' {
+			verbs = append(verbs, rune(format[i]))
+		}
+	}
+	return verbs
+}
+
+func (c *checker) ignored(expr ast.Expr) bool {
+	line := c.fset.Position(expr.Pos()).Line
+	for _, group := range c.file.Comments {
+		for _, comment := range group.List {
+			comment_line := c.fset.Position(comment.Pos()).Line
+			if comment_line != line && comment_line != line-1 {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if strings.HasPrefix(text, ignore_directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// funcName returns the name of the function or the method called, the
+// functions of the package being checked are called without selector
+func funcName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	case *ast.Ident:
+		return fun.Name
+	}
+	return ""
+}
+
+func isFunc(call *ast.CallExpr, pkg string, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == pkg
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStatements fails the test run when a statement of the module is built
+// from outside input
+func TestStatements(t *testing.T) {
+	problems, err := checkTree(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		problems int
+	}{
+		{
+			name: "placeholder",
+			source: `func f(mydb Querier, id string) {
+				mydb.Query("select * from t where id = ?", id)
+			}`,
+		},
+		{
+			name: "sprintf of a parameter",
+			source: `func f(mydb Querier, name string) {
+				db.Query(mydb, fmt.Sprintf("select * from %s", name))
+			}`,
+			problems: 1,
+		},
+		{
+			name: "concatenation in an unqualified call",
+			source: `func f(ctx context.Context, session *Session, name string) {
+				QueryContext(ctx, session, "select * from t where name = '"+name+"'")
+			}`,
+			problems: 1,
+		},
+		{
+			name: "variable built for an unqualified call",
+			source: `func f(mydb Querier, name string) {
+				stmt := fmt.Sprintf("KILL %s", name)
+				RunQuery(mydb, stmt)
+			}`,
+			problems: 1,
+		},
+		{
+			name: "integer printed with a verb",
+			source: `func f(mydb Querier, id int) {
+				RunQuery(mydb, fmt.Sprintf("KILL %d", id))
+			}`,
+		},
+		{
+			name: "constants",
+			source: `const columns = "id, name"
+			func f(mydb Querier) {
+				where := " where id > 0"
+				GetData(mydb, "select "+columns+" from t"+where)
+			}`,
+		},
+		{
+			name: "statement built by a helper",
+			source: `func tableQuery(name string) string {
+				return fmt.Sprintf("select * from %s", name)
+			}
+			func f(mydb Querier, name string) {
+				db.Query(mydb, tableQuery(name))
+				stmt := tableQuery(name)
+				GetData(mydb, stmt)
+			}`,
+			problems: 2,
+		},
+		{
+			name: "variable built by a helper",
+			source: `func killQuery(id string) string {
+				stmt := "KILL " + id
+				return stmt
+			}
+			func f(mydb Querier, id string) {
+				RunQuery(mydb, killQuery(id))
+			}`,
+			problems: 1,
+		},
+		{
+			name: "helper building from constants",
+			source: `func statement(sys bool) string {
+				format := "%s"
+				if sys {
+					format = "sys.format_time(%s)"
+				}
+				return "select " + fmt.Sprintf(format, "TIMER_WAIT") + " from t"
+			}
+			func f(mydb Querier) {
+				db.Query(mydb, statement(true))
+			}`,
+		},
+		// the limits of the check, these are not caught
+		{
+			name: "method building the statement",
+			source: `type view struct{}
+			func (v view) query(name string) string {
+				return fmt.Sprintf("select * from %s", name)
+			}
+			func f(mydb Querier, v view, name string) {
+				db.Query(mydb, v.query(name))
+			}`,
+		},
+		{
+			name: "closure building the statement",
+			source: `func f(mydb Querier, name string) {
+				query := func() string { return "select * from " + name }
+				db.Query(mydb, query())
+			}`,
+		},
+		{
+			name: "statement in a struct field",
+			source: `type request struct{ stmt string }
+			func f(mydb Querier, name string) {
+				r := request{stmt: "select * from " + name}
+				db.Query(mydb, r.stmt)
+			}`,
+		},
+		{
+			name: "ignored",
+			source: `func f(mydb Querier, text string) {
+				// sqlcheck:ignore the text is the statement to explain
+				Query(mydb, "EXPLAIN "+text)
+			}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := "package db\n\n" + test.source + "\n"
+			if err := ioutil.WriteFile(filepath.Join(dir, "query.go"), []byte(source), 0600); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := checkDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != test.problems {
+				t.Errorf("%d problems, want %d:\n%s", len(problems), test.problems, strings.Join(problems, "\n"))
+			}
+		})
+	}
+}